@@ -1,61 +1,370 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mohamedveron/go_app_template/cmd/server/http"
 	"github.com/mohamedveron/go_app_template/internal/api"
 	"github.com/mohamedveron/go_app_template/internal/configs"
+	"github.com/mohamedveron/go_app_template/internal/pkg/cache"
 	"github.com/mohamedveron/go_app_template/internal/pkg/datastore"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
 	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
+	"github.com/mohamedveron/go_app_template/internal/pkg/metrics"
+	"github.com/mohamedveron/go_app_template/internal/pkg/tracing"
 	"github.com/mohamedveron/go_app_template/internal/users"
 	"github.com/mohamedveron/go_app_template/internal/users/persistence"
+	"github.com/mohamedveron/go_app_template/proxy"
+	"github.com/mohamedveron/go_app_template/schemas"
 )
 
+const (
+	// exitOK is returned when the app was shutdown gracefully
+	exitOK = 0
+	// exitStartupFailure is returned when the app failed to start, or the server stopped unexpectedly
+	exitStartupFailure = 1
+	// exitShutdownFailure is returned when one or more dependencies failed to shutdown cleanly
+	exitShutdownFailure = 2
+)
+
+// closer is a dependency which has to be closed while the app is shutting down
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
 func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	defer func() {
+		_ = logger.Sync()
+	}()
 
 	// load configuration
 	cfg, err := configs.New()
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("%+v", err))
-		return
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
 	}
 
-	dscfg, err := cfg.Datastore()
+	logCfg, err := cfg.Logger()
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("%+v", err))
-		return
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
 	}
+	logger.Configure(logCfg)
 
-	pqdriver, err := datastore.NewPostgresService(dscfg)
+	tracingCfg, err := cfg.Tracing()
+	if err != nil {
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingCfg)
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("%+v", err))
-		return
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
+	}
+	// the pending spans are flushed last, so that the ones of the shutdown are exported as well
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		err := shutdownTracing(ctx)
+		if err != nil {
+			logger.Error("failed to flush the spans: ", err)
+		}
+	}()
+
+	// closers are closed in order, after the HTTP server is shutdown
+	closers := make([]closer, 0, 2)
+	defer func() {
+		// closers would be nil after they were closed while shutting down
+		if closers != nil {
+			closeAll(closers, time.Second*5)
+		}
+	}()
+
+	// Postgres is shared by the users & the cache of the completions, if either is stored in it
+	var pqdriver *pgxpool.Pool
+	if cfg.UsersStore == configs.UsersStorePostgres || cfg.LLM.CacheBackend == cache.BackendPostgres {
+		pqdriver, closers, err = newPostgres(cfg, closers)
+		if err != nil {
+			logger.Error(fmt.Sprintf("%+v", err))
+			return exitStartupFailure
+		}
 	}
-	userStore, err := persistence.NewUserPostgresPersistence(pqdriver)
+
+	userStore, closers, err := newUserStore(cfg, pqdriver, closers)
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("%+v", err))
-		return
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
 	}
 	us, err := users.NewService(userStore)
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("%+v", err))
-		return
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
 	}
 
-	a, err := api.NewService(us)
+	httpCfg, err := cfg.HTTP()
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("%+v", err))
-		return
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
 	}
 
-	httpCfg, err := cfg.HTTP()
+	llm, err := newLLMClient(cfg)
+	if err != nil {
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
+	}
+
+	prompts, err := newPrompts(cfg)
+	if err != nil {
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
+	}
+
+	completions, err := newCompletionsCache(cfg, pqdriver)
+	if err != nil {
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
+	}
+
+	a, err := api.NewService(
+		&api.Config{Environment: cfg.Environment, AuthDisabled: httpCfg.AuthDisabled},
+		us,
+		llm,
+		prompts,
+		completions,
+	)
+	if err != nil {
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	server, err := http.New(a, httpCfg)
+	if err != nil {
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Start()
+	}()
+
+	select {
+	case err = <-serverErr:
+		logger.Error(fmt.Sprintf("%+v", err))
+		return exitStartupFailure
+	case <-ctx.Done():
+		// restore the default behaviour, so that a second signal terminates the app immediately
+		stop()
+	}
+
+	logger.Info("shutdown initiated, draining for ", httpCfg.ShutdownDrainDelay.String())
+	server.InitiateShutdown()
+	time.Sleep(httpCfg.ShutdownDrainDelay)
+
+	exitCode := exitOK
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), httpCfg.ShutdownTimeout)
+	defer cancel()
+
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.ErrWithStacktrace(err)
+		exitCode = exitShutdownFailure
+	}
+
+	if !closeAll(closers, httpCfg.ShutdownTimeout) {
+		exitCode = exitShutdownFailure
+	}
+	closers = nil
+
+	if exitCode == exitOK {
+		logger.Info("shutdown completed")
+	}
+
+	return exitCode
+}
+
+// newPostgres initializes the Postgres pool, applies the migrations if enabled, and appends the
+// respective closer
+func newPostgres(cfg *configs.Configs, closers []closer) (*pgxpool.Pool, []closer, error) {
+	dscfg, err := cfg.Datastore()
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("%+v", err))
-		return
+		return nil, closers, err
 	}
 
-	server := http.New(a, httpCfg)
-	server.Start()
+	dscfg.Tracer = tracing.PgxTracer{}
+	pqdriver, err := datastore.NewPostgresService(dscfg)
+	if err != nil {
+		return nil, closers, err
+	}
+	closers = append(closers, closer{
+		name: "postgres",
+		close: func(_ context.Context) error {
+			pqdriver.Close()
+			return nil
+		},
+	})
+
+	err = metrics.RegisterPgxPool(pqdriver)
+	if err != nil {
+		return nil, closers, err
+	}
+
+	if dscfg.AutoMigrate {
+		err = migrate(pqdriver)
+		if err != nil {
+			return nil, closers, err
+		}
+	}
+
+	return pqdriver, closers, nil
+}
+
+// newUserStore initializes the datastore configured for users, and appends respective closers.
+// pqdriver is the Postgres pool, it's nil unless users are stored in Postgres
+func newUserStore(
+	cfg *configs.Configs,
+	pqdriver *pgxpool.Pool,
+	closers []closer,
+) (persistence.UsersPersistence, []closer, error) {
+	switch cfg.UsersStore {
+	case configs.UsersStorePostgres:
+		userStore, err := persistence.NewUserPostgresPersistence(pqdriver)
+		return userStore, closers, err
+
+	case configs.UsersStoreMongoDB:
+		mcfg, err := cfg.Mongo()
+		if err != nil {
+			return nil, closers, err
+		}
+
+		mcfg.PoolMonitor = metrics.MongoPoolMonitor()
+		mcfg.CommandMonitor = tracing.MongoCommandMonitor()
+
+		ctx, cancel := context.WithTimeout(context.Background(), mcfg.ConnectTimeout+mcfg.PingTimeout)
+		defer cancel()
+
+		mongodb, err := datastore.NewMongoService(ctx, mcfg)
+		if err != nil {
+			return nil, closers, err
+		}
+		closers = append(closers, closer{
+			name:  "mongodb",
+			close: mongodb.Disconnect,
+		})
+
+		userStore, err := persistence.NewUserMongoPersistence(ctx, mongodb)
+		return userStore, closers, err
+
+	case configs.UsersStoreMemory:
+		logger.Warn("users are stored in memory, and will be lost when the app stops")
+		return persistence.NewUserMemoryPersistence(), closers, nil
+
+	default:
+		return nil, closers, errors.Validation(fmt.Sprintf("unknown users store '%s'", cfg.UsersStore))
+	}
+}
+
+// newLLMClient initializes the client of the LLM provider configured
+func newLLMClient(cfg *configs.Configs) (proxy.LLMClient, error) {
+	switch cfg.LLM.Provider {
+	case configs.LLMProviderOpenAI:
+		ocfg, err := cfg.OpenAIProxy()
+		if err != nil {
+			return nil, err
+		}
+
+		openAI := proxy.NewOpenAI(ocfg)
+		if cfg.OpenAI.ReadinessCheck {
+			openAI.RegisterHealthCheck()
+		}
+		return openAI, nil
+
+	case configs.LLMProviderFake:
+		if cfg.LLM.FakeFixtures == "" {
+			logger.Warn("completions are scripted by the embedded fixtures, no LLM provider is called")
+			return proxy.NewDefaultFake()
+		}
+
+		logger.Warn("completions are scripted by ", cfg.LLM.FakeFixtures, ", no LLM provider is called")
+		return proxy.NewFakeFromFile(cfg.LLM.FakeFixtures)
+
+	default:
+		return nil, errors.Validation(fmt.Sprintf("unknown LLM provider '%s'", cfg.LLM.Provider))
+	}
+}
+
+// newPrompts loads the prompt templates from LLM_PROMPTS_DIR, or the templates shipped along with
+// the binary when it's empty
+func newPrompts(cfg *configs.Configs) (*proxy.PromptRegistry, error) {
+	fsys := proxy.Prompts()
+	if cfg.LLM.PromptsDir != "" {
+		fsys = os.DirFS(cfg.LLM.PromptsDir)
+	}
+
+	return proxy.LoadPrompts(fsys, cfg.LLM.DefaultPrompt)
+}
+
+// newCompletionsCache initializes the cache configured for the completions of the LLM, it's nil when
+// the cache is disabled. pqdriver is the Postgres pool, it's nil unless the cache is in Postgres
+func newCompletionsCache(cfg *configs.Configs, pqdriver *pgxpool.Pool) (cache.Cache, error) {
+	ccfg, err := cfg.LLMCache()
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.LLM.CacheBackend {
+	case cache.BackendMemory:
+		return cache.NewLRU(ccfg), nil
+	case cache.BackendPostgres:
+		return cache.NewPostgres(pqdriver, ccfg), nil
+	default:
+		logger.Warn("the completions are not cached")
+		return nil, nil
+	}
+}
+
+// migrate applies all the pending migrations
+func migrate(pqdriver *pgxpool.Pool) error {
+	migrator, err := datastore.NewMigrator(pqdriver, schemas.Migrations())
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(context.Background())
+	for _, m := range applied {
+		logger.Info(fmt.Sprintf("applied migration %d (%s)", m.Version, m.Name))
+	}
+
+	return err
+}
+
+// closeAll closes all the closers in order, and reports whether all of them were closed cleanly
+func closeAll(closers []closer, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	clean := true
+	for _, c := range closers {
+		err := c.close(ctx)
+		if err != nil {
+			logger.Error("failed to close ", c.name, ": ", err)
+			clean = false
+		}
+	}
 
+	return clean
 }
@@ -0,0 +1,98 @@
+// Command migrate applies or rolls back the SQL migrations in schemas/migrations, using the same
+// configuration as the app.
+//
+//	go run ./cmd/migrate up        # applies all pending migrations
+//	go run ./cmd/migrate down [n]  # rolls back the last n migrations, 1 by default
+//	go run ./cmd/migrate status    # lists all migrations & when they were applied
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/configs"
+	"github.com/mohamedveron/go_app_template/internal/pkg/datastore"
+	"github.com/mohamedveron/go_app_template/schemas"
+)
+
+const usage = "usage: migrate up | down [n] | status"
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%+v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(usage)
+	}
+
+	cfg, err := configs.New()
+	if err != nil {
+		return err
+	}
+
+	dscfg, err := cfg.Datastore()
+	if err != nil {
+		return err
+	}
+
+	pqdriver, err := datastore.NewPostgresService(dscfg)
+	if err != nil {
+		return err
+	}
+	defer pqdriver.Close()
+
+	migrator, err := datastore.NewMigrator(pqdriver, schemas.Migrations())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps '%s'", args[1])
+			}
+		}
+
+		rolledback, err := migrator.Down(ctx, steps)
+		for _, m := range rolledback {
+			fmt.Printf("rolled back %d_%s\n", m.Version, m.Name)
+		}
+		return err
+
+	case "status":
+		list, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, ms := range list {
+			appliedAt := "pending"
+			if ms.AppliedAt != nil {
+				appliedAt = ms.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d_%s\t%s\n", ms.Version, ms.Name, appliedAt)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf(usage)
+	}
+}
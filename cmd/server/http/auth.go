@@ -0,0 +1,89 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mohamedveron/go_app_template/internal/api"
+	"github.com/mohamedveron/go_app_template/internal/pkg/auth"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+)
+
+// authenticate verifies the bearer token of the request & adds its claims to the request context.
+// Operations which opt out of security in the API contract, with `security: []`, are public &
+// are not authenticated
+func (ht *HTTP) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the generated router sets the scopes only for the operations which require a bearer token
+		_, secured := r.Context().Value(BearerAuthScopes).([]string)
+		if !secured || ht.verifier == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, ok := ht.verify(w, r)
+		if !ok {
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+	})
+}
+
+// requireAdmin allows only the requests with a bearer token of an admin, it's meant for the admin
+// endpoints which are not part of the API contract. All the requests are allowed when
+// authentication is disabled
+func (ht *HTTP) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ht.verifier == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, ok := ht.verify(w, r)
+		if !ok {
+			return
+		}
+
+		for _, role := range claims.Roles {
+			if role == api.RoleAdmin {
+				next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+				return
+			}
+		}
+
+		ht.HandleError(w, r, errors.Forbidden("only admins are permitted"))
+	})
+}
+
+// verify verifies the bearer token of the request, & responds with the error if it's not valid
+func (ht *HTTP) verify(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
+	token, ok := bearerToken(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		ht.HandleError(w, r, errors.Unauthorized("bearer token is required"))
+		return nil, false
+	}
+
+	claims, err := ht.verifier.Verify(r.Context(), token)
+	if err != nil {
+		if errors.KindOf(err) == errors.KindUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		}
+		ht.HandleError(w, r, err)
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// bearerToken returns the token in the Authorization header of the request
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
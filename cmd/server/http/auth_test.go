@@ -0,0 +1,190 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mohamedveron/go_app_template/internal/api"
+	"github.com/mohamedveron/go_app_template/internal/pkg/auth"
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
+)
+
+// newTestVerifier returns a verifier of the tokens signed by the returned function
+func newTestVerifier(t *testing.T) (*auth.Verifier, func(roles ...string) string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(jwks.Close)
+
+	verifier, err := auth.NewVerifier(&auth.Config{
+		JwkURL:   jwks.URL,
+		Issuer:   "https://issuer.example.com/",
+		Audience: "go-app",
+		KeysTTL:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	sign := func(roles ...string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, &auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "42",
+				Issuer:    "https://issuer.example.com/",
+				Audience:  jwt.ClaimStrings{"go-app"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Roles: roles,
+		})
+		token.Header["kid"] = "test"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+
+		return signed
+	}
+
+	return verifier, sign
+}
+
+func TestHTTP_authenticate(t *testing.T) {
+	verifier, sign := newTestVerifier(t)
+	validToken := sign()
+
+	tests := []struct {
+		name          string
+		secured       bool
+		authorization string
+		wantStatus    int
+		wantSubject   string
+	}{
+		{
+			name:          "valid token",
+			secured:       true,
+			authorization: "Bearer " + validToken,
+			wantStatus:    http.StatusOK,
+			wantSubject:   "42",
+		},
+		{
+			name:       "missing token",
+			secured:    true,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "invalid token",
+			secured:       true,
+			authorization: "Bearer " + validToken + "x",
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "not a bearer token",
+			secured:       true,
+			authorization: "Basic dXNlcjpwYXNz",
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:       "public operation",
+			secured:    false,
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ht := &HTTP{verifier: verifier}
+			gotSubject := ""
+			handler := ht.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if claims, ok := auth.ClaimsFromContext(r.Context()); ok {
+					gotSubject = claims.Subject
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/42", nil)
+			if tt.secured {
+				r = r.WithContext(context.WithValue(r.Context(), BearerAuthScopes, []string{}))
+			}
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if gotSubject != tt.wantSubject {
+				t.Errorf("subject in context = '%s', want '%s'", gotSubject, tt.wantSubject)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("expected WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestHTTP_requireAdmin(t *testing.T) {
+	verifier, sign := newTestVerifier(t)
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{
+			name:          "admin",
+			authorization: "Bearer " + sign(api.RoleAdmin),
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:          "not an admin",
+			authorization: "Bearer " + sign(api.RoleUser),
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:       "missing token",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ht := &HTTP{verifier: verifier}
+			handler := ht.requireAdmin(logger.LevelHandler())
+
+			r := httptest.NewRequest(http.MethodGet, "/-/log/level", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,15 +1,106 @@
 package http
 
 import (
-	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
-	"github.com/mohamedveron/go_app_template/proxy"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
 )
 
-func (*HTTP) GetParagraphByTopic(w http.ResponseWriter, r *http.Request, topic string) {
-	gpt := proxy.NewOpenAI("token")
-	msg := gpt.GetMessage(topic)
-	fmt.Printf(msg)
-	w.Write([]byte(msg))
+const (
+	// HeaderCache is the response header telling if the response was served from the cache, HIT,
+	// MISS, or BYPASS when the cache was skipped with Cache-Control: no-cache
+	HeaderCache = "X-Cache"
+	// HeaderPromptTemplate & HeaderPromptTemplateVersion are the response headers of the prompt
+	// template the response was generated with
+	HeaderPromptTemplate        = "X-Prompt-Template"
+	HeaderPromptTemplateVersion = "X-Prompt-Template-Version"
+)
+
+// GetParagraphByTopic implements ServerInterface.
+func (ht *HTTP) GetParagraphByTopic(
+	w http.ResponseWriter,
+	r *http.Request,
+	topic string,
+	params GetParagraphByTopicParams,
+) {
+	template, version := promptTemplate(params.Template, params.TemplateVersion)
+	paragraph, err := ht.apis.GetParagraph(r.Context(), topic, template, version, noCache(r))
+	if err != nil {
+		ht.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set(HeaderCache, strings.ToUpper(paragraph.CacheStatus))
+	w.Header().Set(HeaderPromptTemplate, paragraph.Template)
+	w.Header().Set(HeaderPromptTemplateVersion, strconv.Itoa(paragraph.TemplateVersion))
+	respondJSON(w, http.StatusOK, paragraph.Text)
+}
+
+// promptTemplate returns the name & the version of the prompt template asked for, they're empty &
+// 0 when absent, for the default template & its latest version
+func promptTemplate(template *string, version *int) (string, int) {
+	name, v := "", 0
+	if template != nil {
+		name = *template
+	}
+	if version != nil {
+		v = *version
+	}
+
+	return name, v
+}
+
+// noCache reports whether the request asks not to be served from the cache, with the no-cache
+// directive of Cache-Control
+func noCache(r *http.Request) bool {
+	for _, value := range r.Header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// StreamParagraphByTopic implements ServerInterface. The paragraph is streamed as Server-Sent
+// Events, a token event for every part of it, & a done event with the finish reason, the usage &
+// the prompt template. The upstream completion is cancelled as soon as the client disconnects
+func (ht *HTTP) StreamParagraphByTopic(
+	w http.ResponseWriter,
+	r *http.Request,
+	topic string,
+	params StreamParagraphByTopicParams,
+) {
+	stream, err := newEventStream(w, ht.streamTimeout)
+	if err != nil {
+		ht.HandleError(w, r, err)
+		return
+	}
+
+	template, version := promptTemplate(params.Template, params.TemplateVersion)
+	paragraph, err := ht.apis.StreamParagraph(r.Context(), topic, template, version, func(delta string) error {
+		return stream.Send(eventToken, delta)
+	})
+	if r.Context().Err() != nil {
+		logger.FromContext(r.Context()).Info("client disconnected, the stream is cancelled")
+		return
+	}
+	if err != nil && !stream.Started() {
+		ht.HandleError(w, r, err)
+		return
+	}
+	if err != nil {
+		// the status is already sent, so the error is reported as the last event of the stream
+		status, message, _ := errors.HTTPStatusCodeMessage(err)
+		logger.FromContext(r.Context()).Errorw("stream failed", "status", status, "error", err)
+		_ = stream.Send(eventError, Error{Code: int32(status), Message: message})
+		return
+	}
+
+	_ = stream.Send(eventDone, paragraph)
 }
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+)
+
+const (
+	// preferStrict is the preference (RFC 7240) with which clients opt into strict mode, where
+	// conditional requests are required to update a resource
+	preferStrict = "handling=strict"
+)
+
+// etag returns the strong ETag of the given version of a resource
+func etag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// setETag sets the ETag header of the response
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// ifMatchVersion returns the version expected by the If-Match header, 0 when any version is
+// acceptable. In strict mode, the If-Match header is required
+func ifMatchVersion(w http.ResponseWriter, ifMatch *string, prefer *string) (int64, error) {
+	strict := prefer != nil && prefersStrict(*prefer)
+	if strict {
+		w.Header().Set("Preference-Applied", preferStrict)
+	}
+
+	if ifMatch == nil || strings.TrimSpace(*ifMatch) == "" {
+		if strict {
+			return 0, errors.PreconditionRequired("If-Match header is required in strict mode")
+		}
+		return 0, nil
+	}
+
+	value := strings.TrimSpace(*ifMatch)
+	if value == "*" {
+		return 0, nil
+	}
+
+	if strings.Contains(value, ",") {
+		return 0, errors.Validation("If-Match should have a single ETag")
+	}
+
+	// weak ETags never match, since If-Match requires a strong comparison
+	version, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+	if err != nil || !strings.HasPrefix(value, `"`) || version < 1 {
+		return 0, errors.PreconditionFailed("If-Match does not match the current version")
+	}
+
+	return version, nil
+}
+
+// prefersStrict reports whether the Prefer header has the strict handling preference
+func prefersStrict(prefer string) bool {
+	for _, pref := range strings.Split(prefer, ",") {
+		// parameters of a preference, after ';', are irrelevant here
+		pref, _, _ = strings.Cut(pref, ";")
+		if strings.EqualFold(strings.ReplaceAll(pref, " ", ""), preferStrict) {
+			return true
+		}
+	}
+
+	return false
+}
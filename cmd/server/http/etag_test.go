@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	strPtr := func(s string) *string {
+		return &s
+	}
+
+	tests := []struct {
+		name        string
+		ifMatch     *string
+		prefer      *string
+		wantVersion int64
+		wantKind    errors.Kind
+		wantErr     bool
+	}{
+		{
+			name: "no If-Match",
+		},
+		{
+			name:        "strong ETag",
+			ifMatch:     strPtr(`"3"`),
+			wantVersion: 3,
+		},
+		{
+			name:    "any version",
+			ifMatch: strPtr("*"),
+			prefer:  strPtr(preferStrict),
+		},
+		{
+			name:     "weak ETag never matches",
+			ifMatch:  strPtr(`W/"3"`),
+			wantKind: errors.KindPreconditionFailed,
+			wantErr:  true,
+		},
+		{
+			name:     "malformed ETag",
+			ifMatch:  strPtr("abc"),
+			wantKind: errors.KindPreconditionFailed,
+			wantErr:  true,
+		},
+		{
+			name:     "multiple ETags",
+			ifMatch:  strPtr(`"3", "4"`),
+			wantKind: errors.KindValidation,
+			wantErr:  true,
+		},
+		{
+			name:     "strict mode without If-Match",
+			prefer:   strPtr("respond-async, Handling=Strict; foo=bar"),
+			wantKind: errors.KindPreconditionRequired,
+			wantErr:  true,
+		},
+		{
+			name:   "lenient mode without If-Match",
+			prefer: strPtr("handling=lenient"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := ifMatchVersion(httptest.NewRecorder(), tt.ifMatch, tt.prefer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ifMatchVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if errors.KindOf(err) != tt.wantKind {
+					t.Errorf("ifMatchVersion() error kind = %v, want %v", errors.KindOf(err), tt.wantKind)
+				}
+				return
+			}
+
+			if version != tt.wantVersion {
+				t.Errorf("ifMatchVersion() = %d, want %d", version, tt.wantVersion)
+			}
+		})
+	}
+}
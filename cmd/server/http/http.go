@@ -10,11 +10,14 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/mohamedveron/go_app_template/internal/api"
+	"github.com/mohamedveron/go_app_template/internal/pkg/auth"
+	"github.com/mohamedveron/go_app_template/internal/pkg/buildinfo"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/pkg/health"
 	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
-	"github.com/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/pkg/metrics"
 )
 
 const (
@@ -29,15 +32,33 @@ type Config struct {
 	ReadTimeout       time.Duration
 	WriteTimeout      time.Duration
 	IdleTimeout       time.Duration
-	JwkURL            string
 	AllowedOrigins    []string
+	// AuthDisabled disables authentication of all the APIs, only meant for local development
+	AuthDisabled bool
+	// Auth is the configuration to verify the bearer tokens of the APIs
+	Auth auth.Config
+	// ShutdownDrainDelay is how long the server keeps serving after a shutdown is initiated, with
+	// health reporting unavailable, so that load balancers can deregister the instance
+	ShutdownDrainDelay time.Duration
+	// ShutdownTimeout is the deadline for in-flight requests to complete once the server is shut down
+	ShutdownTimeout time.Duration
+	// ReadinessTimeout is the timeout of each of the health checks run for readiness
+	ReadinessTimeout time.Duration
+	// StreamTimeout is the write timeout of the streamed responses, which replaces WriteTimeout
+	StreamTimeout time.Duration
 }
 
 type HTTP struct {
 	lock   *sync.Mutex
 	server *http.Server
 	// apis has all the APIs, and respective HTTP handlers will call using this
-	apis                      *api.API
+	apis *api.API
+	// verifier verifies the bearer tokens, it's nil when authentication is disabled
+	verifier *auth.Verifier
+	// checks are the health checks of the dependencies, run for readiness
+	checks                    *health.Registry
+	readinessTimeout          time.Duration
+	streamTimeout             time.Duration
 	shutdownInitiated         bool
 	serverStartTime           time.Time
 	liveHealthResponse        map[string]string
@@ -51,8 +72,8 @@ func (ht *HTTP) Start() error {
 		fmt.Sprintf("OK: %s", ht.serverStartTime.Format(time.RFC3339Nano)),
 	)
 	err := ht.server.ListenAndServe()
-	if err != nil {
-		return errors.Wrap(err, "failed to start http server")
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.InternalErr(err, "failed to start http server")
 	}
 	return nil
 }
@@ -75,50 +96,87 @@ func (ht *HTTP) healthResponse() (message []byte, status int) {
 		status = http.StatusServiceUnavailable
 	} else {
 		status = http.StatusOK
-		message, _ = json.Marshal(ht.liveHealthResponse)
+		message, _ = json.Marshal(ht.liveHealth())
 	}
 	ht.lock.Unlock()
 
 	return message, status
 }
 
+// liveHealth returns the health of the app, along with the entries appended. It's called with the
+// lock held
+func (ht *HTTP) liveHealth() map[string]interface{} {
+	response := map[string]interface{}{}
+	if ht.apis != nil {
+		response, _ = ht.apis.Health()
+	}
+
+	for key, value := range ht.liveHealthResponse {
+		response[key] = value
+	}
+
+	return response
+}
+
+// Version responds with the metadata of the build
+func (ht *HTTP) Version(w http.ResponseWriter, _ *http.Request) {
+	respondJSON(w, http.StatusOK, buildinfo.Get())
+}
+
 type HandlerFuncErr func(w http.ResponseWriter, req *http.Request) error
 
-func (ht *HTTP) HandleError(w http.ResponseWriter, err error) {
+// respondJSON writes the given payload as JSON with the status code provided
+func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+// HandleError responds with the HTTP status & message of err, internal errors are logged with the
+// request scoped logger
+func (ht *HTTP) HandleError(w http.ResponseWriter, r *http.Request, err error) {
 	if err == nil {
 		return
 	}
-	/*status, message, _ := HTTPStatusCodeMessage(err)
+	status, message, _ := errors.HTTPStatusCodeMessage(err)
 
 	response := Error{
 		Code:    int32(status),
 		Message: message,
-	}*/
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(500)
-	_ = json.NewEncoder(w).Encode(err)
+	respondJSON(w, status, response)
 
 	// log the full error here for troubleshooting
 	// maybe we just need internal errors to be logged
-	/*if status > errorLogHTTPStatusCodeThreshold {
-		logger.ErrWithStacktrace(err)
-	}*/
+	if status > errorLogHTTPStatusCodeThreshold {
+		logger.FromContext(r.Context()).Errorw("request failed", "status", status, "error", err)
+	}
 }
 func (ht *HTTP) ErrorHandler(fn HandlerFuncErr) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ht.HandleError(w, fn(w, r))
+		ht.HandleError(w, r, fn(w, r))
 	}
 }
-func (ht *HTTP) Shutdown(ctx context.Context) error {
+
+// InitiateShutdown marks the server as shutting down, health reports unavailable from here on,
+// while requests are still served
+func (ht *HTTP) InitiateShutdown() {
 	ht.lock.Lock()
-	ht.shutdownInitiated = true
-	ht.shutdownInitiatedResponse = []byte(fmt.Sprintf("server is shutting down | %s", time.Now().Format(time.RFC3339Nano)))
+	if !ht.shutdownInitiated {
+		ht.shutdownInitiated = true
+		ht.shutdownInitiatedResponse = []byte(fmt.Sprintf("server is shutting down | %s", time.Now().Format(time.RFC3339Nano)))
+	}
 	ht.lock.Unlock()
+}
+
+// Shutdown gracefully shuts down the server, waiting for in-flight requests till ctx is done
+func (ht *HTTP) Shutdown(ctx context.Context) error {
+	ht.InitiateShutdown()
 
 	err := ht.server.Shutdown(ctx)
 	if err != nil {
-		return errors.Wrap(err, "failed to shutdown")
+		return errors.InternalErr(err, "failed to shutdown")
 	}
 	return err
 }
@@ -132,32 +190,74 @@ func (ht *HTTP) Health(w http.ResponseWriter, _ *http.Request) {
 	_, _ = w.Write(msg)
 }
 
-func New(apis *api.API, cfg *Config) *HTTP {
+// Ready reports whether the app is ready to serve, by running the health checks of all the
+// dependencies. It responds with 503 if any critical check fails, or if shutdown is initiated
+func (ht *HTTP) Ready(w http.ResponseWriter, r *http.Request) {
+	ht.lock.Lock()
+	shutdownInitiated := ht.shutdownInitiated
+	ht.lock.Unlock()
+	if shutdownInitiated {
+		respondJSON(w, http.StatusServiceUnavailable, &health.Report{Status: health.StatusDown})
+		return
+	}
+
+	report := ht.checks.Run(r.Context(), ht.readinessTimeout)
+	if !report.Ready() {
+		respondJSON(w, http.StatusServiceUnavailable, report)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+func New(apis *api.API, cfg *Config) (*HTTP, error) {
 	ht := &HTTP{
-		lock: &sync.Mutex{},
-		apis: apis,
+		lock:             &sync.Mutex{},
+		apis:             apis,
+		checks:           health.Default,
+		readinessTimeout: cfg.ReadinessTimeout,
+		streamTimeout:    cfg.StreamTimeout,
 	}
+
+	if cfg.AuthDisabled {
+		logger.Warn("authentication is disabled, all the APIs are public")
+	} else {
+		verifier, err := auth.NewVerifier(&cfg.Auth)
+		if err != nil {
+			return nil, err
+		}
+		ht.verifier = verifier
+	}
+
 	ht.ResetHealthResponse()
 	router := chi.NewRouter()
-	/*if cfg.Environment == config.EnvironmentLocal {
-		router.Use(middleware.Logger)
-	}*/
+	router.Use(traceRequest, requestID, accessLog, recoverer)
 	router.Get("/-/health", ht.Health)
+	router.Get("/-/ready", ht.Ready)
+	router.Get("/-/version", ht.Version)
+	router.Handle("/-/metrics", metrics.Handler())
+	router.With(ht.requireAdmin).Handle("/-/log/level", logger.LevelHandler())
 	v1Router := chi.NewRouter()
-	v1Router.Use(middleware.Recoverer)
 	v1Router.Use(
 		cors.Handler(
 			cors.Options{
 				AllowCredentials: true,
 				AllowedOrigins:   cfg.AllowedOrigins,
-				AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "OPTIONS"},
-				AllowedHeaders:   []string{"Origin", "Content-Type", "Authorization"},
+				AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+				AllowedHeaders:   []string{"Origin", "Content-Type", "Authorization", "If-Match", "Prefer", "Cache-Control", HeaderRequestID},
+				ExposedHeaders:   []string{"ETag", "Preference-Applied", HeaderRequestID, HeaderCache, HeaderPromptTemplate, HeaderPromptTemplateVersion},
 			},
 		),
 	)
 	address := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 	logger.Info("address of the app= ", address)
-	HandlerFromMux(ht, v1Router)
+	HandlerWithOptions(ht, ChiServerOptions{
+		BaseRouter:  v1Router,
+		Middlewares: []MiddlewareFunc{ht.authenticate},
+		ErrorHandlerFunc: func(w http.ResponseWriter, r *http.Request, err error) {
+			ht.HandleError(w, r, errors.ValidationErr(err, err.Error()))
+		},
+	})
 	router.Mount("/api/v1", v1Router)
 	ht.server = &http.Server{
 		Addr:              address,
@@ -165,6 +265,7 @@ func New(apis *api.API, cfg *Config) *HTTP {
 		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
 		ReadTimeout:       cfg.ReadTimeout,
 		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
 	}
-	return ht
+	return ht, nil
 }
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/api"
+	"github.com/mohamedveron/go_app_template/internal/pkg/buildinfo"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/pkg/health"
+	"github.com/mohamedveron/go_app_template/internal/users"
+	"github.com/mohamedveron/go_app_template/internal/users/persistence"
+	"github.com/mohamedveron/go_app_template/proxy"
+)
+
+func TestHTTP_Ready(t *testing.T) {
+	tests := []struct {
+		name              string
+		checkErr          error
+		critical          bool
+		shutdownInitiated bool
+		wantStatus        int
+		wantReport        string
+	}{
+		{
+			name:       "ready",
+			critical:   true,
+			wantStatus: http.StatusOK,
+			wantReport: health.StatusUp,
+		},
+		{
+			name:       "critical dependency down",
+			critical:   true,
+			checkErr:   errors.Internal("dial tcp db.internal:5432: connection refused"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantReport: health.StatusDown,
+		},
+		{
+			name:       "non-critical dependency down",
+			checkErr:   errors.Internal("dial tcp db.internal:5432: connection refused"),
+			wantStatus: http.StatusOK,
+			wantReport: health.StatusDegraded,
+		},
+		{
+			name:              "shutdown initiated",
+			critical:          true,
+			shutdownInitiated: true,
+			wantStatus:        http.StatusServiceUnavailable,
+			wantReport:        health.StatusDown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := health.NewRegistry()
+			checks.Register(health.Check{
+				Name:     "postgres",
+				Critical: tt.critical,
+				Check: func(context.Context) error {
+					return tt.checkErr
+				},
+			})
+			ht := &HTTP{
+				lock:              &sync.Mutex{},
+				checks:            checks,
+				readinessTimeout:  time.Second,
+				shutdownInitiated: tt.shutdownInitiated,
+			}
+
+			w := httptest.NewRecorder()
+			ht.Ready(w, httptest.NewRequest(http.MethodGet, "/-/ready", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			report := &health.Report{}
+			err := json.Unmarshal(w.Body.Bytes(), report)
+			if err != nil {
+				t.Fatalf("failed to decode the report: %v", err)
+			}
+			if report.Status != tt.wantReport {
+				t.Errorf("report status = %s, want %s", report.Status, tt.wantReport)
+			}
+			// the errors of the checks reveal the dependencies, so they're only logged
+			if tt.checkErr != nil && strings.Contains(w.Body.String(), "db.internal") {
+				t.Errorf("report %s has the error of the check", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHTTP_Health(t *testing.T) {
+	us, _ := users.NewService(persistence.NewUserMemoryPersistence())
+	apis, _ := api.NewService(&api.Config{Environment: "docker"}, us, proxy.NewFake(), nil, nil)
+	ht := &HTTP{
+		lock:               &sync.Mutex{},
+		apis:               apis,
+		liveHealthResponse: map[string]string{"http": "OK"},
+	}
+
+	w := httptest.NewRecorder()
+	ht.Health(w, httptest.NewRequest(http.MethodGet, "/-/health", nil))
+
+	got := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("failed to decode the health: %v", err)
+	}
+	if got["env"] != "docker" || got["http"] != "OK" || got["version"] != buildinfo.Get().Version {
+		t.Errorf("unexpected health %v", got)
+	}
+
+	w = httptest.NewRecorder()
+	ht.Version(w, httptest.NewRequest(http.MethodGet, "/-/version", nil))
+
+	version := buildinfo.Info{}
+	err = json.Unmarshal(w.Body.Bytes(), &version)
+	if err != nil {
+		t.Fatalf("failed to decode the version: %v", err)
+	}
+	if version != buildinfo.Get() {
+		t.Errorf("version = %+v, want %+v", version, buildinfo.Get())
+	}
+}
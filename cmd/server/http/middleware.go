@@ -0,0 +1,208 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
+	"github.com/mohamedveron/go_app_template/internal/pkg/metrics"
+	"github.com/mohamedveron/go_app_template/internal/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// HeaderRequestID is the header with which the request ID is received & responded
+	HeaderRequestID = "X-Request-ID"
+	// maxRequestIDLength limits the length of the request IDs received, longer ones are replaced
+	maxRequestIDLength = 128
+)
+
+type requestIDCtxKey struct{}
+
+// RequestID returns the ID of the request in ctx, or an empty string if there's none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// requestID propagates the request ID received in the X-Request-ID header, or generates a new one.
+// The ID is added to the request context & the response headers. The request ID & the IDs of the
+// span of the request, if any, are added to the logger in the request context
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HeaderRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(HeaderRequestID, id)
+
+		ctx := context.WithValue(r.Context(), requestIDCtxKey{}, id)
+		fields := []interface{}{"requestID", id}
+		if traceID, spanID := tracing.IDs(ctx); traceID != "" {
+			fields = append(fields, "traceID", traceID, "spanID", spanID)
+		}
+		ctx = logger.WithContext(ctx, fields...)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", id))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID reports whether the request ID received can be propagated as is. Only printable
+// ASCII is allowed, so that it cannot be used to inject content into the logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	raw := make([]byte, 16)
+	_, _ = rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
+
+// accessLog logs a single line for every request, once it's served, & records the request in the
+// metrics
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			status := responseStatus(ww)
+			route := routePattern(r)
+
+			latency := time.Since(start)
+			metrics.ObserveHTTPRequest(route, r.Method, status, latency)
+
+			fields := []interface{}{
+				"method", r.Method,
+				"route", route,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"latency", latency,
+				"remoteIP", remoteIP(r),
+			}
+			log := logger.FromContext(r.Context())
+			if status > errorLogHTTPStatusCodeThreshold {
+				log.Errorw("request", fields...)
+				return
+			}
+			log.Infow("request", fields...)
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// traceRequest starts a server span for every request, as a child of the span in the W3C
+// traceparent header of the request, if any
+func traceRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Start(
+			ctx,
+			r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(remoteIP(r)),
+			),
+		)
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		// the route pattern is known only after the request is routed
+		route := routePattern(r)
+		status := responseStatus(ww)
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPStatusCode(status))
+		if status > errorLogHTTPStatusCodeThreshold {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// routePattern returns the pattern of the route matched, e.g. /api/v1/users/{id}, it's empty if
+// no route matched
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+
+	return rctx.RoutePattern()
+}
+
+// responseStatus returns the status code of the response
+func responseStatus(ww middleware.WrapResponseWriter) int {
+	// the status is not set if the handler did not write anything
+	if ww.Status() == 0 {
+		return http.StatusOK
+	}
+
+	return ww.Status()
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// recoverer recovers from panics of the handlers, logs the panic with its stack trace & responds
+// with an internal error
+func recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+			// http.ErrAbortHandler is used to abort the response, & is expected to be re-panicked
+			if rvr == http.ErrAbortHandler {
+				panic(rvr)
+			}
+
+			logger.FromContext(r.Context()).Errorw(
+				fmt.Sprintf("panic: %v", rvr),
+				"stacktrace", string(debug.Stack()),
+			)
+
+			status, message, _ := errors.HTTPStatusCodeMessage(errors.Internal("panic"))
+			respondJSON(w, status, Error{Code: int32(status), Message: message})
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
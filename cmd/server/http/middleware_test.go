@@ -0,0 +1,157 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		wantSame  bool
+	}{
+		{
+			name:      "propagated",
+			requestID: "3f1e6c2a-7b7e-4b4d-9c57-0c1f1b2a3d4e",
+			wantSame:  true,
+		},
+		{
+			name: "generated",
+		},
+		{
+			name:      "too long",
+			requestID: strings.Repeat("a", maxRequestIDLength+1),
+		},
+		{
+			name:      "not printable",
+			requestID: "abc\ndef",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotCtxID := ""
+			handler := requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotCtxID = RequestID(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/42", nil)
+			if tt.requestID != "" {
+				r.Header.Set(HeaderRequestID, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			got := w.Header().Get(HeaderRequestID)
+			if got == "" {
+				t.Fatalf("expected %s header", HeaderRequestID)
+			}
+			if got != gotCtxID {
+				t.Errorf("request ID in context = '%s', want '%s'", gotCtxID, got)
+			}
+			if tt.wantSame && got != tt.requestID {
+				t.Errorf("request ID = '%s', want '%s'", got, tt.requestID)
+			}
+			if !tt.wantSame && got == tt.requestID {
+				t.Errorf("request ID '%s' should have been replaced", got)
+			}
+		})
+	}
+}
+
+func TestRecoverer(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	defer logger.Replace(zap.New(core))()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	router := chi.NewRouter()
+	router.Use(traceRequest, requestID, accessLog, recoverer)
+	router.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("something went wrong")
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	r.Header.Set(HeaderRequestID, "abc")
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if w.Header().Get(HeaderRequestID) == "" {
+		t.Errorf("expected %s header", HeaderRequestID)
+	}
+	if strings.Contains(w.Body.String(), "something went wrong") {
+		t.Errorf("panic should not be in the response: %s", w.Body.String())
+	}
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("got %d log entries, want the panic & the access log", len(entries))
+	}
+	panicFields, accessFields := entries[0].ContextMap(), entries[1].ContextMap()
+	if !strings.Contains(panicFields["stacktrace"].(string), "TestRecoverer") {
+		t.Errorf("expected the stack trace of the panic, got %v", panicFields["stacktrace"])
+	}
+	for _, fields := range []map[string]interface{}{panicFields, accessFields} {
+		if fields["requestID"] != "abc" {
+			t.Errorf("requestID = %v, want abc", fields["requestID"])
+		}
+		if fields["traceID"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("traceID = %v, want 4bf92f3577b34da6a3ce929d0e0e4736", fields["traceID"])
+		}
+	}
+	if accessFields["route"] != "/panic" || accessFields["status"] != int64(http.StatusInternalServerError) {
+		t.Errorf("unexpected access log fields %v", accessFields)
+	}
+}
+
+func TestTraceRequest(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	router := chi.NewRouter()
+	router.Use(traceRequest, requestID, recoverer)
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	router.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("something went wrong")
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].Name() != "GET /users/{id}" {
+		t.Errorf("span name = %s, want 'GET /users/{id}'", spans[0].Name())
+	}
+	if got := spans[0].SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the one of the traceparent header", got)
+	}
+	if spans[0].Status().Code == codes.Error {
+		t.Errorf("4xx responses should not fail the span")
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("5xx responses should fail the span, got status %v", spans[1].Status())
+	}
+}
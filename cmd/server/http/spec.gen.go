@@ -6,18 +6,30 @@ package http
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/deepmap/oapi-codegen/pkg/runtime"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi/v5"
 )
 
+const (
+	BearerAuthScopes = "BearerAuth.Scopes"
+)
+
+// Defines values for ListUsersParamsOrderBy.
+const (
+	CreatedAt ListUsersParamsOrderBy = "createdAt"
+	Id        ListUsersParamsOrderBy = "id"
+)
+
 // Error defines model for Error.
 type Error struct {
 	// Code Error code
@@ -32,6 +44,9 @@ type NewUser struct {
 	// Email Email of the User
 	Email *string `json:"email,omitempty"`
 
+	// Mobile Mobile number of the User
+	Mobile *string `json:"mobile,omitempty"`
+
 	// Name Name of the user
 	Name string `json:"name"`
 }
@@ -44,24 +59,132 @@ type User struct {
 	// Id Unique id of the User
 	Id int64 `json:"id"`
 
+	// Mobile Mobile number of the User
+	Mobile *string `json:"mobile,omitempty"`
+
 	// Name Name of the user
 	Name string `json:"name"`
 }
 
+// UserList defines model for UserList.
+type UserList struct {
+	Data []User `json:"data"`
+	Meta struct {
+		// NextCursor Cursor to get the next page, absent on the last page
+		NextCursor *string `json:"nextCursor,omitempty"`
+	} `json:"meta"`
+}
+
+// UserPatch defines model for UserPatch.
+type UserPatch struct {
+	// Email Email of the User
+	Email *string `json:"email"`
+
+	// Mobile Mobile number of the User
+	Mobile *string `json:"mobile"`
+
+	// Name Name of the user
+	Name *string `json:"name,omitempty"`
+}
+
+// GetParagraphByTopicParams defines parameters for GetParagraphByTopic.
+type GetParagraphByTopicParams struct {
+	// Template Name of the prompt template of the paragraph, e.g. paragraph or eli5. The default template is used when absent
+	Template *string `form:"template,omitempty" json:"template,omitempty"`
+
+	// TemplateVersion Version of the prompt template, its latest version is used when absent
+	TemplateVersion *int `form:"templateVersion,omitempty" json:"templateVersion,omitempty"`
+}
+
+// StreamParagraphByTopicParams defines parameters for StreamParagraphByTopic.
+type StreamParagraphByTopicParams struct {
+	// Template Name of the prompt template of the paragraph, e.g. paragraph or eli5. The default template is used when absent
+	Template *string `form:"template,omitempty" json:"template,omitempty"`
+
+	// TemplateVersion Version of the prompt template, its latest version is used when absent
+	TemplateVersion *int `form:"templateVersion,omitempty" json:"templateVersion,omitempty"`
+}
+
+// ListUsersParams defines parameters for ListUsers.
+type ListUsersParams struct {
+	// Cursor nextCursor of the previous page, absent for the first page
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
+
+	// Limit Maximum number of Users in the page, values larger than 100 are capped to 100
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// OrderBy Field to sort the Users by, the ID is always used as the tie breaker
+	OrderBy *ListUsersParamsOrderBy `form:"orderBy,omitempty" json:"orderBy,omitempty"`
+
+	// EmailDomain Returns only the Users with an email of this domain, e.g. example.com
+	EmailDomain *string `form:"emailDomain,omitempty" json:"emailDomain,omitempty"`
+
+	// NamePrefix Returns only the Users whose first or last name start with this prefix, case insensitive
+	NamePrefix *string `form:"namePrefix,omitempty" json:"namePrefix,omitempty"`
+
+	// CreatedFrom Returns only the Users created at or after this time
+	CreatedFrom *time.Time `form:"createdFrom,omitempty" json:"createdFrom,omitempty"`
+
+	// CreatedTo Returns only the Users created before this time
+	CreatedTo *time.Time `form:"createdTo,omitempty" json:"createdTo,omitempty"`
+}
+
+// ListUsersParamsOrderBy defines parameters for ListUsers.
+type ListUsersParamsOrderBy string
+
+// PatchUserParams defines parameters for PatchUser.
+type PatchUserParams struct {
+	// IfMatch ETag of the User, the update is applied only if the User has not been modified since
+	IfMatch *string `json:"If-Match,omitempty"`
+
+	// Prefer handling=strict makes If-Match required, the request fails with 428 without it
+	Prefer *string `json:"Prefer,omitempty"`
+}
+
+// UpdateUserParams defines parameters for UpdateUser.
+type UpdateUserParams struct {
+	// IfMatch ETag of the User, the update is applied only if the User has not been modified since
+	IfMatch *string `json:"If-Match,omitempty"`
+
+	// Prefer handling=strict makes If-Match required, the request fails with 428 without it
+	Prefer *string `json:"Prefer,omitempty"`
+}
+
 // AddUserJSONRequestBody defines body for AddUser for application/json ContentType.
 type AddUserJSONRequestBody = NewUser
 
+// PatchUserApplicationMergePatchPlusJSONRequestBody defines body for PatchUser for application/merge-patch+json ContentType.
+type PatchUserApplicationMergePatchPlusJSONRequestBody = UserPatch
+
+// UpdateUserJSONRequestBody defines body for UpdateUser for application/json ContentType.
+type UpdateUserJSONRequestBody = NewUser
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
 	// Returns a Paragraph
 	// (GET /openai/{topic})
-	GetParagraphByTopic(w http.ResponseWriter, r *http.Request, topic string)
+	GetParagraphByTopic(w http.ResponseWriter, r *http.Request, topic string, params GetParagraphByTopicParams)
+	// Streams a Paragraph
+	// (GET /openai/{topic}/stream)
+	StreamParagraphByTopic(w http.ResponseWriter, r *http.Request, topic string, params StreamParagraphByTopicParams)
+	// Lists Users
+	// (GET /users)
+	ListUsers(w http.ResponseWriter, r *http.Request, params ListUsersParams)
 	// Creates a new user
 	// (POST /users)
 	AddUser(w http.ResponseWriter, r *http.Request)
+	// Deletes a User by ID
+	// (DELETE /users/{id})
+	DeleteUser(w http.ResponseWriter, r *http.Request, id int64)
 	// Returns a User by ID
 	// (GET /users/{id})
 	FindUserByID(w http.ResponseWriter, r *http.Request, id int64)
+	// Partially updates a User by ID
+	// (PATCH /users/{id})
+	PatchUser(w http.ResponseWriter, r *http.Request, id int64, params PatchUserParams)
+	// Replaces a User by ID
+	// (PUT /users/{id})
+	UpdateUser(w http.ResponseWriter, r *http.Request, id int64, params UpdateUserParams)
 }
 
 // Unimplemented server implementation that returns http.StatusNotImplemented for each endpoint.
@@ -70,7 +193,19 @@ type Unimplemented struct{}
 
 // Returns a Paragraph
 // (GET /openai/{topic})
-func (_ Unimplemented) GetParagraphByTopic(w http.ResponseWriter, r *http.Request, topic string) {
+func (_ Unimplemented) GetParagraphByTopic(w http.ResponseWriter, r *http.Request, topic string, params GetParagraphByTopicParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Streams a Paragraph
+// (GET /openai/{topic}/stream)
+func (_ Unimplemented) StreamParagraphByTopic(w http.ResponseWriter, r *http.Request, topic string, params StreamParagraphByTopicParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Lists Users
+// (GET /users)
+func (_ Unimplemented) ListUsers(w http.ResponseWriter, r *http.Request, params ListUsersParams) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
@@ -80,12 +215,30 @@ func (_ Unimplemented) AddUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Deletes a User by ID
+// (DELETE /users/{id})
+func (_ Unimplemented) DeleteUser(w http.ResponseWriter, r *http.Request, id int64) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Returns a User by ID
 // (GET /users/{id})
 func (_ Unimplemented) FindUserByID(w http.ResponseWriter, r *http.Request, id int64) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Partially updates a User by ID
+// (PATCH /users/{id})
+func (_ Unimplemented) PatchUser(w http.ResponseWriter, r *http.Request, id int64, params PatchUserParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Replaces a User by ID
+// (PUT /users/{id})
+func (_ Unimplemented) UpdateUser(w http.ResponseWriter, r *http.Request, id int64, params UpdateUserParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // ServerInterfaceWrapper converts contexts to parameters.
 type ServerInterfaceWrapper struct {
 	Handler            ServerInterface
@@ -110,8 +263,154 @@ func (siw *ServerInterfaceWrapper) GetParagraphByTopic(w http.ResponseWriter, r
 		return
 	}
 
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetParagraphByTopicParams
+
+	// ------------- Optional query parameter "template" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "template", r.URL.Query(), &params.Template)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "template", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "templateVersion" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "templateVersion", r.URL.Query(), &params.TemplateVersion)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "templateVersion", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetParagraphByTopic(w, r, topic, params)
+	}))
+
+	for i := len(siw.HandlerMiddlewares) - 1; i >= 0; i-- {
+		handler = siw.HandlerMiddlewares[i](handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// StreamParagraphByTopic operation middleware
+func (siw *ServerInterfaceWrapper) StreamParagraphByTopic(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "topic" -------------
+	var topic string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "topic", runtime.ParamLocationPath, chi.URLParam(r, "topic"), &topic)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "topic", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params StreamParagraphByTopicParams
+
+	// ------------- Optional query parameter "template" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "template", r.URL.Query(), &params.Template)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "template", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "templateVersion" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "templateVersion", r.URL.Query(), &params.TemplateVersion)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "templateVersion", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.StreamParagraphByTopic(w, r, topic, params)
+	}))
+
+	for i := len(siw.HandlerMiddlewares) - 1; i >= 0; i-- {
+		handler = siw.HandlerMiddlewares[i](handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// ListUsers operation middleware
+func (siw *ServerInterfaceWrapper) ListUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListUsersParams
+
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", r.URL.Query(), &params.Cursor)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "cursor", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "orderBy" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "orderBy", r.URL.Query(), &params.OrderBy)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "orderBy", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "emailDomain" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "emailDomain", r.URL.Query(), &params.EmailDomain)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "emailDomain", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "namePrefix" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "namePrefix", r.URL.Query(), &params.NamePrefix)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "namePrefix", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "createdFrom" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "createdFrom", r.URL.Query(), &params.CreatedFrom)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "createdFrom", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "createdTo" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "createdTo", r.URL.Query(), &params.CreatedTo)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "createdTo", Err: err})
+		return
+	}
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetParagraphByTopic(w, r, topic)
+		siw.Handler.ListUsers(w, r, params)
 	}))
 
 	for i := len(siw.HandlerMiddlewares) - 1; i >= 0; i-- {
@@ -125,6 +424,8 @@ func (siw *ServerInterfaceWrapper) GetParagraphByTopic(w http.ResponseWriter, r
 func (siw *ServerInterfaceWrapper) AddUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.AddUser(w, r)
 	}))
@@ -136,6 +437,34 @@ func (siw *ServerInterfaceWrapper) AddUser(w http.ResponseWriter, r *http.Reques
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
+// DeleteUser operation middleware
+func (siw *ServerInterfaceWrapper) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, chi.URLParam(r, "id"), &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteUser(w, r, id)
+	}))
+
+	for i := len(siw.HandlerMiddlewares) - 1; i >= 0; i-- {
+		handler = siw.HandlerMiddlewares[i](handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // FindUserByID operation middleware
 func (siw *ServerInterfaceWrapper) FindUserByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -151,6 +480,8 @@ func (siw *ServerInterfaceWrapper) FindUserByID(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.FindUserByID(w, r, id)
 	}))
@@ -162,6 +493,148 @@ func (siw *ServerInterfaceWrapper) FindUserByID(w http.ResponseWriter, r *http.R
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
+// PatchUser operation middleware
+func (siw *ServerInterfaceWrapper) PatchUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, chi.URLParam(r, "id"), &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PatchUserParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch string
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "If-Match", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "If-Match", Err: err})
+			return
+		}
+
+		params.IfMatch = &IfMatch
+
+	}
+
+	// ------------- Optional header parameter "Prefer" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Prefer")]; found {
+		var Prefer string
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "Prefer", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "Prefer", runtime.ParamLocationHeader, valueList[0], &Prefer)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "Prefer", Err: err})
+			return
+		}
+
+		params.Prefer = &Prefer
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PatchUser(w, r, id, params)
+	}))
+
+	for i := len(siw.HandlerMiddlewares) - 1; i >= 0; i-- {
+		handler = siw.HandlerMiddlewares[i](handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// UpdateUser operation middleware
+func (siw *ServerInterfaceWrapper) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, chi.URLParam(r, "id"), &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params UpdateUserParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch string
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "If-Match", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "If-Match", Err: err})
+			return
+		}
+
+		params.IfMatch = &IfMatch
+
+	}
+
+	// ------------- Optional header parameter "Prefer" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Prefer")]; found {
+		var Prefer string
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "Prefer", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "Prefer", runtime.ParamLocationHeader, valueList[0], &Prefer)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "Prefer", Err: err})
+			return
+		}
+
+		params.Prefer = &Prefer
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.UpdateUser(w, r, id, params)
+	}))
+
+	for i := len(siw.HandlerMiddlewares) - 1; i >= 0; i-- {
+		handler = siw.HandlerMiddlewares[i](handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
 type UnescapedCookieParamError struct {
 	ParamName string
 	Err       error
@@ -278,12 +751,27 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/openai/{topic}", wrapper.GetParagraphByTopic)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/openai/{topic}/stream", wrapper.StreamParagraphByTopic)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/users", wrapper.ListUsers)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/users", wrapper.AddUser)
 	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/users/{id}", wrapper.DeleteUser)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/users/{id}", wrapper.FindUserByID)
 	})
+	r.Group(func(r chi.Router) {
+		r.Patch(options.BaseURL+"/users/{id}", wrapper.PatchUser)
+	})
+	r.Group(func(r chi.Router) {
+		r.Put(options.BaseURL+"/users/{id}", wrapper.UpdateUser)
+	})
 
 	return r
 }
@@ -291,20 +779,45 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/8xVTW/jNhD9K8S0R1Vyk6IHnerspoWBIlk0m9PCh6k4kliIH0uO4jUM/feClJQ4tuK2",
-	"aIHuyTI5H2/mvRkeoLLaWUOGA5QHCFVLGtPnrffWxw/nrSPPitJxZSXFX0mh8sqxsgbK0Vikuwxq6zUy",
-	"lKAMX19BBrx3NP6lhjwMGWgKAZs3A83Xz66BvTINDEMGnj73ypOE8hNMCWfz7ZDBHe0eAy0AJ42qW0gY",
-	"j4WtBbckkudZ0gwM6gWsd6hp9uwXPU/gpjAR5IwQu+6+hvLTAb71VEMJ3xQvfBQTGcVc0pCd1qTkOapH",
-	"oz73JJQ8KeqYlR9/WGDlBKySsB22QzxWprYj94ax4qNuAjrFhPqnsMOmIZ8rC3O74Bcr1s6J9YeN+Eio",
-	"IYPeR5+W2YWyKI58huykirUIqF1HyZtb5NjhIDBVIzAINIK+jCZshSRtTWCPTKIm5N5TEMqk+u8dmRjl",
-	"Ol+J4KhStaowpcmgUxWZkLidQK8dVi2Jq3x1hne32+WYrnPrm2LyDcWvm3e3dw+3313lq7xl3cVqmLwO",
-	"9/UD+SdV0WLRRbIpIhOKu2jzMN6lGgNbH7X9RD6MLfk+X+WrGNs6MugUlHCdjjJwyG0SRJHuVHFg61Q1",
-	"xKOG+FwkvxH33sR+fkCPjUfXit8xkBTWCBTJG1Imn1q1kZFP4mfrm/3HycahR01MPiQhv86TAkWCAqGv",
-	"WohagjLhfdHJnO1Ffex7yqZlFMGfjtU2GgdnY/vj/dVqNeuTTCoXnesmmos/QsRyuBDvTH6xjQKVmLOM",
-	"Aq2x7/gfJbo01uOCXUjeG/riqGKSgiabDEKvNfr9MnfJoog7KLXD2bDA+TtPyGmGDO3mhfWa4bWU07aI",
-	"XFDgGyv3/1nBz3vsvOQ01WwFShl/4tjOA/BaFcO/ZP4SvrfAxVZ9lUpYYPRFCMVByb+zAFLrj2Y/KNN0",
-	"JDbvz9TxszJJHjf7dHlx8Dfv4/Mz01oTvzX8Sl6c/L9+tbb/gyIev1ZFnNK6jzymMIH800zV62ctvTb5",
-	"0bsU35ZhO/wZAAD//+w19m0cCgAA",
+	"H4sIAAAAAAAC/+xae28buRH/KgO2QFt0LSlOml4FFKgfSevDJTFOTntFbMCj3VktL7vkhuRaFgx992JI",
+	"rp4rO8qjcFH/JYlLDn8z85vhDFd3ItVVrRUpZ8XwTti0oAr911fGaMNfaqNrMk6SH051RvyZkU2NrJ3U",
+	"SgzDZPDPEpFrU6ETQyGVe34oEuFmNYWfNCEj5omoyFqc7BTUPl4stc5INRHzeSIMfWqkoUwMP4i4YTv9",
+	"ap6ItzR9b6kDOFUoy44NeRh0Dq4g8Cu3Nk1Epcey7ED7xo+DaqoxmYeEKKw6RLzFitqVTefKDZ29GNa0",
+	"VRPL8l0uhh/uxG8N5WIoftNfOrUfPdpv7TJPNg0js21U75X81BDIbEOpVde+fNHh2g2wMhNX8xbsT9K6",
+	"bb9k6NDDcFT5gfu0iCq026IxOAt8crgtWtGtO2mM1WZbwzAOTsOEnFeRZ0ONE0oAx5aUA638gxJteNDp",
+	"mziix79S6rYM4LWL+Fo7nKNLi68iqGrKEsfMSGca+kaEfVDoFxOYjWIpbYx0sxF7Mih8TGjIHDXOG2Ps",
+	"f71uCfbjvy5EEvIRywpPl7IL52rhBUuV65CYlMPUrVhSYC0dYfU3O8XJhExPatFqIf6u4aiu4ej8DC4I",
+	"K5GIxpRRsB32+ytr5smGykdgsapL8qtdgY4Vt4DekoAWUAHdhilOQ0aVVtYZdAQ5oWsMWZCBW+9qUizl",
+	"eW8AtqZU5jJFv00iSpmSst7kEfRRjWlBcNgbbOGdTqc99I972kz6ca3t/3R28urt6NXBYW/QK1xV+ugh",
+	"U9l3+YjMjUypU+m+n9Nng0vHnBCj8MzraJ02JBJxQ8YGkzzrDXoDlq1rUlhLMRTP/VAianSF93ffP5P9",
+	"O6drmc55aEJum1E/k2uMYnueo8GJwbqAMVrKOCAR/Ore8pkFNAQpq57BeBaeJ4DAcUjWwVS64lJdn/CM",
+	"gxOtnNHlEJQ+8GuuwX6UtfXe8AMJoMpAOgv1YntDdYkprcxiMNS7VBcFhR1BWshlWVLGvkWoja5qB46q",
+	"ukRHCUgHttBNmcGYAMFKNSkJSql8+DQ1U8UVdKnSAg2mjowFLEs9jWoVtCLs2WDAgxnl2JSud8l84WTi",
+	"uXOWMcHJLUx0PLtgiN4ZBiti2f7EWDd80MNpsIQmLQQHlxh6By4Dx0VJyywX0kVI0ezNrRx5X8rYMNNi",
+	"uIWeAPUmveVv0AaolH/qAVs+6r9cLi3HYgbTglRM460anxoysxU94hKxF/R/BsbvQJ942vA36yAGxxdA",
+	"ipusIaukklVTieGzjkP3it1ha80Rz5MPB4M2JfJuXCTUdRkzS/9Xq9Wy2OtSeyvjceQCSmh3EYkoCDNP",
+	"ozvxy4GPre1I/sfZBcgNh8IULVgyN5RBbnS1GnhvzkajhD18/O/zo9GoXeufhnUfZV1TtmYZUmyWD7yZ",
+	"SASLEIkIAsRV14n9y8G5d9vBRUuB4T4M3dZmQooMOvaxdMUauM/Y/aB193A/sn0RkCVngpN9+OzFlfvK",
+	"s9AzdPCnUXRbU8rIKM5JhG2qCs2sO+P7GRsnRt86Q1jtPDhG/vH9BwegBT74yByMSDl4dcMa9PjTzNic",
+	"bisFgbSXyheEyLKvnf5I6hqIVybe0n7+j6N3b4EU9yQZOC4m0QLXfwl/sZoxWJDudyt+igdI0AtIZTbI",
+	"Q7jOtKLtTXKppOXzCK1WiR/ycKDhDggum8Hg8OUKWy7Vgi5eBuenNjEt4GlzqcK2Cq69f+LGbQBGvLwo",
+	"R1laqGQ2xVnXsRN88HTyPJ08MZtwJPQ9mw6W4bvH0RMWsdqbh1DgqBWPKJF1ZKCQyBobz8oHKl5uM1lX",
+	"X2VDxZ2iVJMY+qUjYxOw2rhYZdqUVMYTtMnIBGYuu14WhJfKy2y5sNLw5pqrS14du14ue+2u5rcr2Lmj",
+	"90gfiu91TIG/dCN1Y9c77lybqKtZ9txdhE29sP0i6A3eMpFXut9g5diKBSA3WDbEkWS42XEFKl9rhxaj",
+	"roMFnw0GO3CVspJuDdaCmoeD5P5Y2gL8WlLp92OPL1p1C+NZyPxnp95f5RRn0bsYOhQnCcaG8COZHTg9",
+	"X45n3UiFzESyqKv8j9QQn1dHrrOk2sVnrcrZCu72jKHllYa0kOkKpYrZNrbNvVRXO5D7xad+zX7u3wWq",
+	"0LYlnDaB77wVWIfGtSevtMzYXN4mkKIlkMqSstLJm10M5Y9zv+SbwIwOAPQoMXeendKCk9XOIAlrXhtd",
+	"rWFY3OJlXH5GAV8LbEy5NvS5mC70/oi+tst56GLR30925Pyj9az8mM4bhmyXsGptO06XE29yCwiKpu0d",
+	"3XoiP8qyeAsYL02OdTb7Ztot7p639eNxznCYZfH+A9rLpfUyb/6dnd9pfAbX3fK+usDJgw0by01YrTFB",
+	"2z+c5Qdv+FTn4abOfOXo7s0Qj6lR66DSsrzp38lsHoxSUldXferHFxelK61ZvAw7O91iZlgTyXlvjXF2",
+	"2oZouHT1GDobCZlt0aszGe18ybGdiV50vENhJAFG9phyxqYXZmz2efJQZfr5Lnstlc8mx7Oz0/2clpNL",
+	"i/+az75/Ann/lEB23vSsk69u34ltGNAreC/9kvbGxN+/vCEzIfBv2OD3P78+gT8//8vLP2xR1E/YP6kE",
+	"e38ngm4VXEyRDSYUFDH4wp+95+1RzkAu50GBFpR2MCZSUOlM5jzNSpUusAcmLtG3rNqvVi1QZaVUk7/y",
+	"lNRBhR9phaGtNQLw9nVMuEHyTntx+IP/ohsXSNyJjatour/Xu/rcwqVifhx4tv1x/xj3tOlive/U7Kqz",
+	"lnRJ+LRsyjK0lmCo0jfxTVLOyx5FqfN4MtWLZ4ffP0tdtF7iW/NFhLRdFS1v0NRCC85SLw5/+P7YFmaT",
+	"dhFBDKSNMZ3RYyonztE4iWU5g2YjWS9ye9NZWMQ3qliWy1jwIbRPrREOiKdM/v+Xyb95Cxrf8XsN6VZa",
+	"5+9VFbXv0p6S9FOS/l9N0stsu5abV/+Y5VPm6l+yPlxxMPo39TGhrv/jiAXZ3spfhrCWYn41/88AasoQ",
+	"TlQqAAA=",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+)
+
+const (
+	eventToken = "token"
+	eventDone  = "done"
+	eventError = "error"
+)
+
+// eventStream writes Server-Sent Events to the response, flushing every event as soon as it's
+// written. The headers are written with the first event, so that the handler can still respond
+// with an error until then
+type eventStream struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+// newEventStream extends the write deadline of the response by timeout, since streams outlive the
+// write timeout of the server
+func newEventStream(w http.ResponseWriter, timeout time.Duration) (*eventStream, error) {
+	rc := http.NewResponseController(w)
+	err := rc.SetWriteDeadline(time.Now().Add(timeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, errors.InternalErr(err, "failed to extend the write deadline")
+	}
+
+	return &eventStream{w: w, rc: rc}, nil
+}
+
+// Send writes the event with data JSON encoded, & flushes it
+func (es *eventStream) Send(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return errors.InternalErr(err, "failed to encode the event")
+	}
+
+	if !es.started {
+		es.started = true
+		h := es.w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		// disables response buffering of nginx based proxies
+		h.Set("X-Accel-Buffering", "no")
+		es.w.WriteHeader(http.StatusOK)
+	}
+
+	_, err = fmt.Fprintf(es.w, "event: %s\ndata: %s\n\n", event, payload)
+	if err != nil {
+		return errors.InternalErr(err, "failed to write the event")
+	}
+
+	err = es.rc.Flush()
+	if err != nil {
+		return errors.InternalErr(err, "failed to flush the event")
+	}
+
+	return nil
+}
+
+// Started reports whether any event was sent, after which the status & headers can't be changed
+func (es *eventStream) Started() bool {
+	return es.started
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEventStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	stream, err := newEventStream(w, time.Minute)
+	if err != nil {
+		t.Fatalf("newEventStream() error = %v", err)
+	}
+	if stream.Started() {
+		t.Error("Started() = true, before any event is sent")
+	}
+
+	_ = stream.Send(eventToken, "Hello\n")
+	_ = stream.Send(eventDone, map[string]string{"finishReason": "stop"})
+
+	if !w.Flushed {
+		t.Error("expected the events to be flushed")
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+
+	// the data is JSON encoded, so that new lines in it can't break the event
+	want := "event: token\ndata: \"Hello\\n\"\n\nevent: done\ndata: {\"finishReason\":\"stop\"}\n\n"
+	if w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body.String(), want)
+	}
+}
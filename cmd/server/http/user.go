@@ -1,13 +1,248 @@
 package http
 
-import "net/http"
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/users/domain"
+)
 
 // AddUser implements ServerInterface.
-func (*HTTP) AddUser(w http.ResponseWriter, r *http.Request) {
-	panic("unimplemented")
+func (ht *HTTP) AddUser(w http.ResponseWriter, r *http.Request) {
+	payload := new(AddUserJSONRequestBody)
+	err := json.NewDecoder(r.Body).Decode(payload)
+	if err != nil {
+		ht.HandleError(w, r, errors.ValidationErr(err, "invalid request body"))
+		return
+	}
+
+	u, err := ht.apis.CreateUser(r.Context(), toDomainUser(payload))
+	if err != nil {
+		ht.HandleError(w, r, err)
+		return
+	}
+
+	setETag(w, u.Version)
+	respondJSON(w, http.StatusOK, toUserResponse(u))
 }
 
 // FindUserByID implements ServerInterface.
-func (*HTTP) FindUserByID(w http.ResponseWriter, r *http.Request, id int64) {
-	panic("unimplemented")
+func (ht *HTTP) FindUserByID(w http.ResponseWriter, r *http.Request, id int64) {
+	u, err := ht.apis.ReadUserByID(r.Context(), id)
+	if err != nil {
+		ht.HandleError(w, r, err)
+		return
+	}
+
+	setETag(w, u.Version)
+	respondJSON(w, http.StatusOK, toUserResponse(u))
+}
+
+// ListUsers implements ServerInterface.
+func (ht *HTTP) ListUsers(w http.ResponseWriter, r *http.Request, params ListUsersParams) {
+	query := &domain.UsersQuery{
+		CreatedFrom: params.CreatedFrom,
+		CreatedTo:   params.CreatedTo,
+	}
+	if params.OrderBy != nil {
+		query.OrderBy = domain.UsersOrderBy(*params.OrderBy)
+	}
+	if params.EmailDomain != nil {
+		query.EmailDomain = *params.EmailDomain
+	}
+	if params.NamePrefix != nil {
+		query.NamePrefix = *params.NamePrefix
+	}
+	if params.Limit != nil {
+		if *params.Limit < 1 {
+			ht.HandleError(w, r, errors.Validation("limit should be greater than 0"))
+			return
+		}
+		query.Limit = *params.Limit
+	}
+
+	cursor := ""
+	if params.Cursor != nil {
+		cursor = *params.Cursor
+	}
+
+	page, err := ht.apis.ListUsers(r.Context(), query, cursor)
+	if err != nil {
+		ht.HandleError(w, r, err)
+		return
+	}
+
+	resp := &UserList{
+		Data: make([]User, 0, len(page.Users)),
+	}
+	for _, u := range page.Users {
+		resp.Data = append(resp.Data, *toUserResponse(u))
+	}
+	if page.NextCursor != "" {
+		resp.Meta.NextCursor = &page.NextCursor
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// UpdateUser implements ServerInterface.
+func (ht *HTTP) UpdateUser(w http.ResponseWriter, r *http.Request, id int64, params UpdateUserParams) {
+	version, err := ifMatchVersion(w, params.IfMatch, params.Prefer)
+	if err != nil {
+		ht.HandleError(w, r, err)
+		return
+	}
+
+	payload := new(UpdateUserJSONRequestBody)
+	err = json.NewDecoder(r.Body).Decode(payload)
+	if err != nil {
+		ht.HandleError(w, r, errors.ValidationErr(err, "invalid request body"))
+		return
+	}
+
+	u := toDomainUser(payload)
+	u.ID = id
+	u.Version = version
+
+	u, err = ht.apis.UpdateUser(r.Context(), u)
+	if err != nil {
+		ht.HandleError(w, r, err)
+		return
+	}
+
+	setETag(w, u.Version)
+	respondJSON(w, http.StatusOK, toUserResponse(u))
+}
+
+// PatchUser implements ServerInterface. The request body is a JSON merge patch (RFC 7396), where
+// absent fields are left unchanged & null clears the field
+func (ht *HTTP) PatchUser(w http.ResponseWriter, r *http.Request, id int64, params PatchUserParams) {
+	version, err := ifMatchVersion(w, params.IfMatch, params.Prefer)
+	if err != nil {
+		ht.HandleError(w, r, err)
+		return
+	}
+
+	payload := map[string]json.RawMessage{}
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		ht.HandleError(w, r, errors.ValidationErr(err, "invalid request body"))
+		return
+	}
+
+	patch, err := toDomainUserPatch(payload)
+	if err != nil {
+		ht.HandleError(w, r, err)
+		return
+	}
+
+	u, err := ht.apis.PatchUser(r.Context(), id, version, patch)
+	if err != nil {
+		ht.HandleError(w, r, err)
+		return
+	}
+
+	setETag(w, u.Version)
+	respondJSON(w, http.StatusOK, toUserResponse(u))
+}
+
+// DeleteUser implements ServerInterface.
+func (ht *HTTP) DeleteUser(w http.ResponseWriter, r *http.Request, id int64) {
+	err := ht.apis.DeleteUser(r.Context(), id)
+	if err != nil {
+		ht.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// toDomainUser maps the API contract of a new user to domain.User. The contract has a single
+// name, which is split into first & last name at the first whitespace
+func toDomainUser(nu *NewUser) *domain.User {
+	u := new(domain.User)
+	u.FirstName, u.LastName = splitName(nu.Name)
+	if nu.Email != nil {
+		u.Email = *nu.Email
+	}
+	if nu.Mobile != nil {
+		u.Mobile = *nu.Mobile
+	}
+
+	return u
+}
+
+// toDomainUserPatch maps a JSON merge patch of UserPatch to domain.UserPatch. A null email or
+// mobile clears the field, while name cannot be cleared
+func toDomainUserPatch(payload map[string]json.RawMessage) (*domain.UserPatch, error) {
+	fields := UserPatch{}
+	for key, raw := range payload {
+		var target **string
+		switch key {
+		case "name":
+			if string(raw) == "null" {
+				return nil, errors.Validation("name cannot be null")
+			}
+			target = &fields.Name
+		case "email":
+			target = &fields.Email
+		case "mobile":
+			target = &fields.Mobile
+		default:
+			return nil, errors.Validation(fmt.Sprintf("unknown field '%s'", key))
+		}
+
+		err := json.Unmarshal(raw, target)
+		if err != nil {
+			return nil, errors.ValidationErr(err, fmt.Sprintf("invalid value of '%s'", key))
+		}
+
+		// null clears the field
+		if *target == nil {
+			empty := ""
+			*target = &empty
+		}
+	}
+
+	patch := &domain.UserPatch{
+		Mobile: fields.Mobile,
+		Email:  fields.Email,
+	}
+	if fields.Name != nil {
+		firstName, lastName := splitName(*fields.Name)
+		patch.FirstName, patch.LastName = &firstName, &lastName
+	}
+
+	return patch, nil
+}
+
+// toUserResponse maps domain.User to the User model of the API contract
+func toUserResponse(u *domain.User) *User {
+	resp := &User{
+		Id:   u.ID,
+		Name: strings.TrimSpace(u.FirstName + " " + u.LastName),
+	}
+	if u.Email != "" {
+		email := u.Email
+		resp.Email = &email
+	}
+	if u.Mobile != "" {
+		mobile := u.Mobile
+		resp.Mobile = &mobile
+	}
+
+	return resp
+}
+
+func splitName(name string) (firstName string, lastName string) {
+	parts := strings.SplitN(strings.TrimSpace(name), " ", 2)
+	firstName = parts[0]
+	if len(parts) > 1 {
+		lastName = strings.TrimSpace(parts[1])
+	}
+
+	return firstName, lastName
 }
@@ -0,0 +1,603 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mohamedveron/go_app_template/internal/api"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/users"
+	"github.com/mohamedveron/go_app_template/internal/users/domain"
+	"github.com/mohamedveron/go_app_template/internal/users/persistence"
+	"github.com/mohamedveron/go_app_template/proxy"
+)
+
+func newTestUserHTTP(t *testing.T) (*HTTP, *users.UsersService) {
+	t.Helper()
+
+	us, _ := users.NewService(persistence.NewUserMemoryPersistence())
+	apis, _ := api.NewService(&api.Config{AuthDisabled: true}, us, proxy.NewFake(), nil, nil)
+
+	return &HTTP{apis: apis}, us
+}
+
+func TestHTTP_AddUser(t *testing.T) {
+	ht, us := newTestUserHTTP(t)
+
+	w := httptest.NewRecorder()
+	body := `{"name":"Jane van Doe","email":"jane.doe@example.com","mobile":"+31612345678"}`
+	ht.AddUser(w, httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	got := User{}
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("failed to decode the user: %v", err)
+	}
+	if got.Id == 0 {
+		t.Error("the ID of the user created should be in the response")
+	}
+	email, mobile := "jane.doe@example.com", "+31612345678"
+	want := User{Id: got.Id, Name: "Jane van Doe", Email: &email, Mobile: &mobile}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AddUser() = %+v, want %+v", got, want)
+	}
+
+	// the name is split into the first & the last name of the user stored
+	u, err := us.ReadByID(context.Background(), got.Id)
+	if err != nil {
+		t.Fatalf("ReadByID() error = %v", err)
+	}
+	if u.FirstName != "Jane" || u.LastName != "van Doe" || u.Email != email || u.Mobile != mobile {
+		t.Errorf("user stored = %+v", u)
+	}
+}
+
+func TestHTTP_AddUser_invalidBody(t *testing.T) {
+	ht, _ := newTestUserHTTP(t)
+
+	w := httptest.NewRecorder()
+	ht.AddUser(w, httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(`{"name":`)))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHTTP_FindUserByID(t *testing.T) {
+	ht, _ := newTestUserHTTP(t)
+
+	w := httptest.NewRecorder()
+	body := `{"name":"Jane Doe","email":"jane.doe@example.com"}`
+	ht.AddUser(w, httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body)))
+	created := User{}
+	err := json.Unmarshal(w.Body.Bytes(), &created)
+	if err != nil {
+		t.Fatalf("failed to decode the user: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		id         int64
+		wantStatus int
+		wantUser   *User
+	}{
+		{name: "found", id: created.Id, wantStatus: http.StatusOK, wantUser: &created},
+		{name: "not found", id: created.Id + 1, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ht.FindUserByID(w, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil), tt.id)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantUser == nil {
+				return
+			}
+
+			got := User{}
+			err := json.Unmarshal(w.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatalf("failed to decode the user: %v", err)
+			}
+			if !reflect.DeepEqual(got, *tt.wantUser) {
+				t.Errorf("FindUserByID() = %+v, want %+v", got, *tt.wantUser)
+			}
+		})
+	}
+}
+
+// addTestUser creates a user through AddUser, & returns the user of the response
+func addTestUser(t *testing.T, ht *HTTP, body string) User {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	ht.AddUser(w, httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("AddUser() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	u := User{}
+	err := json.Unmarshal(w.Body.Bytes(), &u)
+	if err != nil {
+		t.Fatalf("failed to decode the user: %v", err)
+	}
+
+	return u
+}
+
+func TestHTTP_ListUsers(t *testing.T) {
+	ht, _ := newTestUserHTTP(t)
+	for i := 0; i < users.MaxListLimit+1; i++ {
+		emailDomain := "example.com"
+		if i%2 == 1 {
+			emailDomain = "other.com"
+		}
+		addTestUser(t, ht, fmt.Sprintf(`{"name":"User %d","email":"user%d@%s"}`, i, i, emailDomain))
+	}
+
+	first := listTestUsers(t, ht, ListUsersParams{Limit: intPtr(2)}, http.StatusOK)
+	if first.Meta.NextCursor == nil {
+		t.Fatal("the first page should have nextCursor")
+	}
+	tampered := (*first.Meta.NextCursor)[:len(*first.Meta.NextCursor)-3]
+	byCreatedAt := CreatedAt
+
+	tests := []struct {
+		name           string
+		params         ListUsersParams
+		wantStatus     int
+		wantCount      int
+		wantNextCursor bool
+	}{
+		{
+			name:       "limit 0",
+			params:     ListUsersParams{Limit: intPtr(0)},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "negative limit",
+			params:     ListUsersParams{Limit: intPtr(-1)},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "limit above the maximum is capped",
+			params:         ListUsersParams{Limit: intPtr(users.MaxListLimit + 50)},
+			wantStatus:     http.StatusOK,
+			wantCount:      users.MaxListLimit,
+			wantNextCursor: true,
+		},
+		{
+			name:       "invalid cursor",
+			params:     ListUsersParams{Cursor: strPtr("not a cursor")},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "tampered cursor",
+			params:     ListUsersParams{Cursor: &tampered},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "cursor of another orderBy",
+			params:     ListUsersParams{Cursor: first.Meta.NextCursor, OrderBy: &byCreatedAt},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "next page",
+			params:         ListUsersParams{Cursor: first.Meta.NextCursor, Limit: intPtr(2)},
+			wantStatus:     http.StatusOK,
+			wantCount:      2,
+			wantNextCursor: true,
+		},
+		{
+			name:       "email domain",
+			params:     ListUsersParams{EmailDomain: strPtr("other.com"), Limit: intPtr(users.MaxListLimit)},
+			wantStatus: http.StatusOK,
+			wantCount:  (users.MaxListLimit + 1) / 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := listTestUsers(t, ht, tt.params, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if len(got.Data) != tt.wantCount {
+				t.Errorf("len(data) = %d, want %d", len(got.Data), tt.wantCount)
+			}
+			if (got.Meta.NextCursor != nil) != tt.wantNextCursor {
+				t.Errorf("nextCursor = %v, want %v", got.Meta.NextCursor, tt.wantNextCursor)
+			}
+			for _, u := range got.Data {
+				if tt.params.EmailDomain != nil && !strings.HasSuffix(*u.Email, "@"+*tt.params.EmailDomain) {
+					t.Errorf("email %s is not of the domain %s", *u.Email, *tt.params.EmailDomain)
+				}
+			}
+		})
+	}
+
+	t.Run("all pages", func(t *testing.T) {
+		seen := map[int64]bool{}
+		params := ListUsersParams{Limit: intPtr(30)}
+		for pages := 1; ; pages++ {
+			page := listTestUsers(t, ht, params, http.StatusOK)
+			for _, u := range page.Data {
+				if seen[u.Id] {
+					t.Fatalf("user %d is in more than one page", u.Id)
+				}
+				seen[u.Id] = true
+			}
+
+			// the last page has no nextCursor
+			if page.Meta.NextCursor == nil {
+				if pages != 4 || len(seen) != users.MaxListLimit+1 {
+					t.Errorf("got %d users in %d pages, want %d in 4", len(seen), pages, users.MaxListLimit+1)
+				}
+				return
+			}
+			params.Cursor = page.Meta.NextCursor
+		}
+	})
+}
+
+// listTestUsers lists the users through ListUsers, & returns the list when the status is 200
+func listTestUsers(t *testing.T, ht *HTTP, params ListUsersParams, wantStatus int) UserList {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	ht.ListUsers(w, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil), params)
+	if w.Code != wantStatus {
+		t.Fatalf("ListUsers() status = %d, want %d: %s", w.Code, wantStatus, w.Body.String())
+	}
+
+	list := UserList{}
+	if wantStatus != http.StatusOK {
+		return list
+	}
+
+	err := json.Unmarshal(w.Body.Bytes(), &list)
+	if err != nil {
+		t.Fatalf("failed to decode the users: %v", err)
+	}
+
+	return list
+}
+
+func TestHTTP_UpdateUser(t *testing.T) {
+	ht, _ := newTestUserHTTP(t)
+	created := addTestUser(t, ht, `{"name":"Jane Doe","email":"jane.doe@example.com","mobile":"+31612345678"}`)
+	addTestUser(t, ht, `{"name":"John Doe"}`)
+
+	mobile := "+31600000000"
+	tests := []struct {
+		name       string
+		id         int64
+		body       string
+		wantStatus int
+		wantUser   *User
+	}{
+		{
+			name:       "all fields",
+			id:         created.Id,
+			body:       `{"name":"Jane Roe","email":"jane.roe@example.com","mobile":"+31600000000"}`,
+			wantStatus: http.StatusOK,
+			wantUser:   &User{Id: created.Id, Name: "Jane Roe", Email: strPtr("jane.roe@example.com"), Mobile: &mobile},
+		},
+		{
+			name:       "without email, which clears it",
+			id:         created.Id,
+			body:       `{"name":"Jane Roe","mobile":"+31600000000"}`,
+			wantStatus: http.StatusOK,
+			wantUser:   &User{Id: created.Id, Name: "Jane Roe", Mobile: &mobile},
+		},
+		{
+			name:       "empty name",
+			id:         created.Id,
+			body:       `{"name":" ","email":"jane.roe@example.com"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid body",
+			id:         created.Id,
+			body:       `{"name":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown user",
+			id:         created.Id + 100,
+			body:       `{"name":"Jane Roe"}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPut, "/api/v1/users", strings.NewReader(tt.body))
+			ht.UpdateUser(w, r, tt.id, UpdateUserParams{})
+
+			assertUserResponse(t, w, tt.wantStatus, tt.wantUser)
+		})
+	}
+}
+
+func TestHTTP_PatchUser(t *testing.T) {
+	ht, _ := newTestUserHTTP(t)
+	created := addTestUser(t, ht, `{"name":"Jane Doe","email":"jane.doe@example.com","mobile":"+31612345678"}`)
+	addTestUser(t, ht, `{"name":"John Doe"}`)
+
+	// the patches are applied in order, on the same user
+	tests := []struct {
+		name       string
+		id         int64
+		body       string
+		wantStatus int
+		wantUser   *User
+	}{
+		{
+			name:       "name only, the rest is kept",
+			id:         created.Id,
+			body:       `{"name":"Jane Roe"}`,
+			wantStatus: http.StatusOK,
+			wantUser:   &User{Id: created.Id, Name: "Jane Roe", Email: created.Email, Mobile: created.Mobile},
+		},
+		{
+			name:       "null email clears it",
+			id:         created.Id,
+			body:       `{"email":null}`,
+			wantStatus: http.StatusOK,
+			wantUser:   &User{Id: created.Id, Name: "Jane Roe", Mobile: created.Mobile},
+		},
+		{
+			name:       "empty name",
+			id:         created.Id,
+			body:       `{"name":""}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid patch",
+			id:         created.Id,
+			body:       `{"age":30}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown user",
+			id:         created.Id + 100,
+			body:       `{"name":"Jane Roe"}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPatch, "/api/v1/users", strings.NewReader(tt.body))
+			ht.PatchUser(w, r, tt.id, PatchUserParams{})
+
+			assertUserResponse(t, w, tt.wantStatus, tt.wantUser)
+		})
+	}
+}
+
+func TestHTTP_UpdateUser_conditional(t *testing.T) {
+	ht, _ := newTestUserHTTP(t)
+	created := addTestUser(t, ht, `{"name":"Jane Doe","email":"jane.doe@example.com"}`)
+
+	// the requests are sent in order, on the same user at version 1
+	tests := []struct {
+		name       string
+		method     string
+		ifMatch    *string
+		prefer     *string
+		wantStatus int
+		wantETag   string
+	}{
+		{name: "current version", method: http.MethodPut, ifMatch: strPtr(`"1"`), wantStatus: http.StatusOK, wantETag: `"2"`},
+		{name: "stale version", method: http.MethodPut, ifMatch: strPtr(`"1"`), wantStatus: http.StatusPreconditionFailed},
+		{name: "patch current version", method: http.MethodPatch, ifMatch: strPtr(`"2"`), wantStatus: http.StatusOK, wantETag: `"3"`},
+		{name: "patch stale version", method: http.MethodPatch, ifMatch: strPtr(`"2"`), wantStatus: http.StatusPreconditionFailed},
+		{name: "strict without If-Match", method: http.MethodPut, prefer: strPtr(preferStrict), wantStatus: http.StatusPreconditionRequired},
+		{name: "patch strict without If-Match", method: http.MethodPatch, prefer: strPtr(preferStrict), wantStatus: http.StatusPreconditionRequired},
+		{name: "any version", method: http.MethodPut, ifMatch: strPtr("*"), prefer: strPtr(preferStrict), wantStatus: http.StatusOK, wantETag: `"4"`},
+		{name: "patch any version", method: http.MethodPatch, ifMatch: strPtr("*"), wantStatus: http.StatusOK, wantETag: `"5"`},
+		{name: "without If-Match", method: http.MethodPatch, wantStatus: http.StatusOK, wantETag: `"6"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sendConditionalUpdate(ht, tt.method, created.Id, tt.ifMatch, tt.prefer)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+		})
+	}
+}
+
+func TestHTTP_UpdateUser_concurrent(t *testing.T) {
+	ht, _ := newTestUserHTTP(t)
+	created := addTestUser(t, ht, `{"name":"Jane Doe","email":"jane.doe@example.com"}`)
+
+	const total = 10
+	wg := sync.WaitGroup{}
+	statuses := make(chan int, total)
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		method := http.MethodPut
+		if i%2 == 1 {
+			method = http.MethodPatch
+		}
+		go func() {
+			defer wg.Done()
+			statuses <- sendConditionalUpdate(ht, method, created.Id, strPtr(`"1"`), nil).Code
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	// all the writes are at the same version, only one of them succeeds
+	counts := map[int]int{}
+	for status := range statuses {
+		counts[status]++
+	}
+	if counts[http.StatusOK] != 1 || counts[http.StatusPreconditionFailed] != total-1 {
+		t.Errorf("statuses = %v, want 1 %d & %d %d", counts, http.StatusOK, total-1, http.StatusPreconditionFailed)
+	}
+
+	w := httptest.NewRecorder()
+	ht.FindUserByID(w, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil), created.Id)
+	if got := w.Header().Get("ETag"); got != `"2"` {
+		t.Errorf("ETag after the concurrent writes = %q, want %q", got, `"2"`)
+	}
+}
+
+// sendConditionalUpdate sends a PUT or a PATCH of the user, with the given If-Match & Prefer
+func sendConditionalUpdate(ht *HTTP, method string, id int64, ifMatch *string, prefer *string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	if method == http.MethodPatch {
+		r := httptest.NewRequest(method, "/api/v1/users", strings.NewReader(`{"mobile":"+31600000000"}`))
+		ht.PatchUser(w, r, id, PatchUserParams{IfMatch: ifMatch, Prefer: prefer})
+		return w
+	}
+
+	r := httptest.NewRequest(method, "/api/v1/users", strings.NewReader(`{"name":"Jane Roe"}`))
+	ht.UpdateUser(w, r, id, UpdateUserParams{IfMatch: ifMatch, Prefer: prefer})
+
+	return w
+}
+
+func TestHTTP_DeleteUser(t *testing.T) {
+	ht, _ := newTestUserHTTP(t)
+	created := addTestUser(t, ht, `{"name":"Jane Doe","email":"jane.doe@example.com"}`)
+
+	w := httptest.NewRecorder()
+	ht.DeleteUser(w, httptest.NewRequest(http.MethodDelete, "/api/v1/users", nil), created.Id)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DeleteUser() status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("DeleteUser() body = %q, want none", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	ht.FindUserByID(w, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil), created.Id)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("FindUserByID() after delete status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w = httptest.NewRecorder()
+	ht.DeleteUser(w, httptest.NewRequest(http.MethodDelete, "/api/v1/users", nil), created.Id)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("DeleteUser() again status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestToDomainUserPatch(t *testing.T) {
+	empty := ""
+	tests := []struct {
+		name    string
+		payload string
+		want    *domain.UserPatch
+		wantErr bool
+	}{
+		{
+			name:    "absent fields are kept",
+			payload: `{}`,
+			want:    &domain.UserPatch{},
+		},
+		{
+			name:    "name is split",
+			payload: `{"name":"Jane van Doe"}`,
+			want:    &domain.UserPatch{FirstName: strPtr("Jane"), LastName: strPtr("van Doe")},
+		},
+		{
+			name:    "null clears email & mobile",
+			payload: `{"email":null,"mobile":null}`,
+			want:    &domain.UserPatch{Email: &empty, Mobile: &empty},
+		},
+		{
+			name:    "email & mobile",
+			payload: `{"email":"jane.doe@example.com","mobile":"+31612345678"}`,
+			want:    &domain.UserPatch{Email: strPtr("jane.doe@example.com"), Mobile: strPtr("+31612345678")},
+		},
+		{
+			name:    "null name",
+			payload: `{"name":null}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			payload: `{"age":30}`,
+			wantErr: true,
+		},
+		{
+			name:    "mistyped field",
+			payload: `{"mobile":31612345678}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := map[string]json.RawMessage{}
+			err := json.Unmarshal([]byte(tt.payload), &payload)
+			if err != nil {
+				t.Fatalf("invalid payload: %v", err)
+			}
+
+			got, err := toDomainUserPatch(payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("toDomainUserPatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if errors.KindOf(err) != errors.KindValidation {
+					t.Errorf("toDomainUserPatch() error = %v, want validation", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("toDomainUserPatch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// assertUserResponse checks the status of the response, & its user when wantUser is not nil
+func assertUserResponse(t *testing.T, w *httptest.ResponseRecorder, wantStatus int, wantUser *User) {
+	t.Helper()
+
+	if w.Code != wantStatus {
+		t.Fatalf("status = %d, want %d: %s", w.Code, wantStatus, w.Body.String())
+	}
+	if wantUser == nil {
+		return
+	}
+
+	got := User{}
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("failed to decode the user: %v", err)
+	}
+	if !reflect.DeepEqual(got, *wantUser) {
+		t.Errorf("user = %+v, want %+v", got, *wantUser)
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func intPtr(i int) *int {
+	return &i
+}
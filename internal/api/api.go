@@ -3,34 +3,66 @@ package api
 import (
 	"time"
 
+	"github.com/mohamedveron/go_app_template/internal/pkg/buildinfo"
+	"github.com/mohamedveron/go_app_template/internal/pkg/cache"
 	"github.com/mohamedveron/go_app_template/internal/users"
+	"github.com/mohamedveron/go_app_template/proxy"
 )
 
 var (
 	now = time.Now()
 )
 
+type Config struct {
+	// Environment the app is running in, e.g. local, docker or production
+	Environment string
+	// AuthDisabled skips authorization of all the APIs, it's set when authentication is disabled
+	AuthDisabled bool
+}
+
 // API holds all the dependencies required to expose APIs. And each API is a function with *API as its receiver
 type API struct {
 	users *users.UsersService
+	llm   proxy.LLMClient
+	// prompts are the templates of the prompts of the LLM
+	prompts *proxy.PromptRegistry
+	// completions caches the completions of the LLM, it's nil when the cache is disabled
+	completions  cache.Cache
+	authDisabled bool
+	environment  string
 }
 
 // Health returns the health of the app along with other info like version
 func (a *API) Health() (map[string]interface{}, error) {
+	info := buildinfo.Get()
 	return map[string]interface{}{
-		"env":        "testing",
-		"version":    "v0.1.0",
-		"commit":     "<git commit hash>",
+		"env":        a.environment,
+		"version":    info.Version,
+		"commit":     info.Commit,
+		"buildTime":  info.BuildTime,
+		"commitTime": info.CommitTime,
+		"goVersion":  info.GoVersion,
+		"dirty":      info.Dirty,
 		"status":     "all systems up and running",
-		"startedAt":  now.String(),
-		"releasedOn": now.String(),
+		"startedAt":  now.Format(time.RFC3339Nano),
 	}, nil
-
 }
 
-// NewService returns a new instance of API with all the dependencies initialized
-func NewService(us *users.UsersService) (*API, error) {
+// NewService returns a new instance of API with all the dependencies initialized. completions is
+// the cache of the completions of the LLM, it's disabled when nil
+func NewService(
+	cfg *Config,
+	us *users.UsersService,
+	llm proxy.LLMClient,
+	prompts *proxy.PromptRegistry,
+	completions cache.Cache,
+) (*API, error) {
 	return &API{
-		users: us,
+		users:        us,
+		llm:          llm,
+		prompts:      prompts,
+		completions:  completions,
+		authDisabled: cfg.AuthDisabled,
+		environment:  cfg.Environment,
 	}, nil
 }
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/buildinfo"
+	"github.com/mohamedveron/go_app_template/internal/users"
+	"github.com/mohamedveron/go_app_template/internal/users/persistence"
+	"github.com/mohamedveron/go_app_template/proxy"
+)
+
+func TestAPI_Health(t *testing.T) {
+	us, _ := users.NewService(persistence.NewUserMemoryPersistence())
+	a, _ := NewService(&Config{Environment: "production"}, us, proxy.NewFake(), nil, nil)
+
+	got, err := a.Health()
+	if err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+
+	info := buildinfo.Get()
+	want := map[string]interface{}{
+		"env":       "production",
+		"version":   info.Version,
+		"commit":    info.Commit,
+		"goVersion": info.GoVersion,
+		"dirty":     info.Dirty,
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %v, want %v", key, got[key], value)
+		}
+	}
+}
+
+func TestCompletionKey(t *testing.T) {
+	prompt := proxy.Prompt{System: "Be brief", User: "Go  language", MaxTokens: 100}
+	key := completionKey("openai/gpt-4", &prompt)
+
+	spaced := prompt
+	spaced.User = " go language "
+	if got := completionKey("openai/gpt-4", &spaced); got != key {
+		t.Error("prompts differing only by case & spaces should share the key")
+	}
+
+	other := prompt
+	other.User = "Go"
+	if got := completionKey("openai/gpt-4", &other); got == key {
+		t.Error("different prompts should not share the key")
+	}
+	if got := completionKey("openai/gpt-4o", &prompt); got == key {
+		t.Error("the completions of different models should not share the key")
+	}
+
+	limited := prompt
+	limited.MaxTokens = 50
+	if got := completionKey("openai/gpt-4", &limited); got == key {
+		t.Error("the completions of different parameters should not share the key")
+	}
+
+	instructed := prompt
+	instructed.System = "Be verbose"
+	if got := completionKey("openai/gpt-4", &instructed); got == key {
+		t.Error("the completions of different system messages should not share the key")
+	}
+}
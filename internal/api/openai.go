@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
+	"github.com/mohamedveron/go_app_template/internal/pkg/metrics"
+	"github.com/mohamedveron/go_app_template/proxy"
+)
+
+// The cache statuses of the paragraphs
+const (
+	// CacheHit is the status of a paragraph served from the cache
+	CacheHit = "hit"
+	// CacheMiss is the status of a paragraph generated since it was not cached, or the cache is
+	// disabled
+	CacheMiss = "miss"
+	// CacheBypass is the status of a paragraph generated since the cache was bypassed on request
+	CacheBypass = "bypass"
+)
+
+// completionsCache is the name of the cache of the completions, in the metrics
+const completionsCache = "completions"
+
+// Paragraph is a paragraph generated about a topic
+type Paragraph struct {
+	Text string
+	// CacheStatus tells if the paragraph was served from the cache, one of the Cache* values
+	CacheStatus string
+	// Template & TemplateVersion identify the prompt template the paragraph was generated with
+	Template        string
+	TemplateVersion int
+}
+
+// StreamedParagraph is the outcome of a paragraph streamed about a topic, once it ends
+type StreamedParagraph struct {
+	proxy.Completion
+	// Template & TemplateVersion identify the prompt template the paragraph was generated with
+	Template        string `json:"template"`
+	TemplateVersion int    `json:"templateVersion"`
+}
+
+// GetParagraph is the API to generate a paragraph about the given topic, with the version of the
+// prompt template. The default template is used when template is empty, & its latest version when
+// version is 0. Paragraphs are served from the cache, unless bypassCache is true, in which case the
+// paragraph generated replaces the cached one
+func (a *API) GetParagraph(
+	ctx context.Context,
+	topic string,
+	template string,
+	version int,
+	bypassCache bool,
+) (*Paragraph, error) {
+	err := a.authorize(ctx, "GetParagraph", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, prompt, err := a.prompt(ctx, topic, template, version)
+	if err != nil {
+		return nil, err
+	}
+
+	paragraph := &Paragraph{
+		CacheStatus:     CacheMiss,
+		Template:        prompt.Template,
+		TemplateVersion: prompt.Version,
+	}
+	if a.completions == nil {
+		paragraph.Text, err = a.llm.Complete(ctx, prompt)
+		if err != nil {
+			return nil, err
+		}
+		logger.FromContext(ctx).Infow("paragraph generated", "cache", paragraph.CacheStatus)
+		return paragraph, nil
+	}
+
+	key := completionKey(a.llm.Fingerprint(), prompt)
+	paragraph.CacheStatus = CacheBypass
+	if !bypassCache {
+		paragraph.CacheStatus = CacheMiss
+		text, ok, err := a.completions.Get(ctx, key)
+		if err != nil {
+			// the paragraph is generated when the cache fails, rather than failing the request
+			logger.FromContext(ctx).Warnw("failed to read the cached completion", "error", err.Error())
+		}
+		if ok {
+			paragraph.Text, paragraph.CacheStatus = text, CacheHit
+			metrics.ObserveCacheRequest(completionsCache, paragraph.CacheStatus)
+			logger.FromContext(ctx).Infow("paragraph served from the cache")
+			return paragraph, nil
+		}
+	}
+	metrics.ObserveCacheRequest(completionsCache, paragraph.CacheStatus)
+
+	paragraph.Text, err = a.llm.Complete(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.completions.Set(ctx, key, paragraph.Text)
+	if err != nil {
+		logger.FromContext(ctx).Warnw("failed to cache the completion", "error", err.Error())
+	}
+	logger.FromContext(ctx).Infow("paragraph generated", "cache", paragraph.CacheStatus)
+
+	return paragraph, nil
+}
+
+// prompt renders the prompt of the topic with the version of the template. The template & its
+// version are added to the logger of the returned context, so that they're in all the logs of the
+// paragraph
+func (a *API) prompt(
+	ctx context.Context,
+	topic string,
+	template string,
+	version int,
+) (context.Context, *proxy.Prompt, error) {
+	pt, err := a.prompts.Get(template, version)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	ctx = logger.WithContext(ctx, "promptTemplate", pt.Name, "promptVersion", pt.Version)
+	prompt, err := pt.Render(topic)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	return ctx, prompt, nil
+}
+
+// completionKey returns the key of the cached completion of the prompt, by the fingerprint of the
+// LLM client. The user message is normalized, so that prompts differing only by case & spaces share
+// it
+func completionKey(fingerprint string, prompt *proxy.Prompt) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(prompt.User)), " ")
+	params := fmt.Sprintf("%s\x00%g\x00%d\x00%s", prompt.Model, prompt.Temperature, prompt.MaxTokens, prompt.System)
+	sum := sha256.Sum256([]byte(fingerprint + "\x00" + params + "\x00" + normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// StreamParagraph is the API to stream a paragraph about the given topic, with the version of the
+// prompt template like GetParagraph. onDelta is called with every part of the paragraph as soon as
+// it's generated
+func (a *API) StreamParagraph(
+	ctx context.Context,
+	topic string,
+	template string,
+	version int,
+	onDelta func(delta string) error,
+) (*StreamedParagraph, error) {
+	err := a.authorize(ctx, "StreamParagraph", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, prompt, err := a.prompt(ctx, topic, template, version)
+	if err != nil {
+		return nil, err
+	}
+
+	completion, err := a.llm.Stream(ctx, prompt, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infow("paragraph streamed", "finishReason", completion.FinishReason)
+
+	return &StreamedParagraph{
+		Completion:      *completion,
+		Template:        prompt.Template,
+		TemplateVersion: prompt.Version,
+	}, nil
+}
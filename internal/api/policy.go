@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/auth"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+)
+
+// Roles of the subjects, as in the 'roles' claim of the token
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Scopes granted to the tokens, as in the 'scope' claim of the token
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	ScopeOpenAI     = "openai"
+)
+
+// permission is what a subject requires to call an API
+type permission struct {
+	// scopes are all required
+	scopes []string
+	// roles are the roles allowed, any one of them is required. Any role is allowed when empty
+	roles []string
+	// ownerOrAdmin allows only the user who owns the resource, i.e. the subject of the token is
+	// the ID of the user, or an admin
+	ownerOrAdmin bool
+}
+
+// policies is the permission required for every API, by the name of the API method. APIs not in
+// this table are denied to everyone
+var policies = map[string]permission{
+	"CreateUser": {
+		scopes: []string{ScopeUsersWrite},
+	},
+	"ReadUserByID": {
+		scopes:       []string{ScopeUsersRead},
+		roles:        []string{RoleAdmin, RoleUser},
+		ownerOrAdmin: true,
+	},
+	"ReadUserByEmail": {
+		scopes:       []string{ScopeUsersRead},
+		roles:        []string{RoleAdmin, RoleUser},
+		ownerOrAdmin: true,
+	},
+	"ListUsers": {
+		scopes: []string{ScopeUsersRead},
+		roles:  []string{RoleAdmin},
+	},
+	"UpdateUser": {
+		scopes:       []string{ScopeUsersWrite},
+		roles:        []string{RoleAdmin, RoleUser},
+		ownerOrAdmin: true,
+	},
+	"PatchUser": {
+		scopes:       []string{ScopeUsersWrite},
+		roles:        []string{RoleAdmin, RoleUser},
+		ownerOrAdmin: true,
+	},
+	"DeleteUser": {
+		scopes: []string{ScopeUsersWrite},
+		roles:  []string{RoleAdmin},
+	},
+	"GetParagraph": {
+		scopes: []string{ScopeOpenAI},
+	},
+	"StreamParagraph": {
+		scopes: []string{ScopeOpenAI},
+	},
+}
+
+// authorize checks the claims in ctx against the policy of the API. ownerID is the ID of the user
+// who owns the resource, it's only used by the APIs which are allowed for the owner
+func (a *API) authorize(ctx context.Context, api string, ownerID int64) error {
+	claims, perm, err := a.permitted(ctx, api)
+	if err != nil {
+		return err
+	}
+
+	if !ownerOrAdmin(claims, perm, ownerID) {
+		return errors.Forbidden("only the owner or an admin is permitted")
+	}
+
+	return nil
+}
+
+// permitted checks the claims in ctx against the scopes & the roles of the policy of the API, but
+// not the owner, for the APIs whose owner is known only after reading the resource. The claims are
+// nil when the authorization is disabled
+func (a *API) permitted(ctx context.Context, api string) (*auth.Claims, permission, error) {
+	if a.authDisabled {
+		return nil, permission{}, nil
+	}
+
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, permission{}, errors.Unauthorized("authentication is required")
+	}
+
+	perm, ok := policies[api]
+	if !ok {
+		return nil, permission{}, errors.Forbidden(fmt.Sprintf("%s is not permitted", api))
+	}
+
+	scopes := claims.Scopes()
+	for _, scope := range perm.scopes {
+		if !contains(scopes, scope) {
+			return nil, permission{}, errors.Forbidden(fmt.Sprintf("scope '%s' is required", scope))
+		}
+	}
+
+	if len(perm.roles) > 0 && !containsAny(claims.Roles, perm.roles) {
+		return nil, permission{}, errors.Forbidden("role is not permitted")
+	}
+
+	return claims, perm, nil
+}
+
+// ownerOrAdmin reports whether the claims are of the owner of the resource or of an admin, it's
+// always true for the APIs not restricted to the owner, or when the authorization is disabled
+func ownerOrAdmin(claims *auth.Claims, perm permission, ownerID int64) bool {
+	if claims == nil || !perm.ownerOrAdmin {
+		return true
+	}
+
+	return contains(claims.Roles, RoleAdmin) || claims.Subject == strconv.FormatInt(ownerID, 10)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsAny(list []string, values []string) bool {
+	for _, value := range values {
+		if contains(list, value) {
+			return true
+		}
+	}
+
+	return false
+}
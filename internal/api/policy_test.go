@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mohamedveron/go_app_template/internal/pkg/auth"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/users"
+	"github.com/mohamedveron/go_app_template/internal/users/domain"
+	"github.com/mohamedveron/go_app_template/internal/users/persistence"
+	"github.com/mohamedveron/go_app_template/proxy"
+)
+
+func withClaims(subject string, scope string, roles ...string) context.Context {
+	return auth.WithClaims(context.Background(), &auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+		Scope:            scope,
+		Roles:            roles,
+	})
+}
+
+func TestPolicies(t *testing.T) {
+	// every API, except the ones which are not authorized, should have a policy
+	public := map[string]bool{"Health": true}
+
+	apiType := reflect.TypeOf(&API{})
+	for i := 0; i < apiType.NumMethod(); i++ {
+		name := apiType.Method(i).Name
+		if _, ok := policies[name]; !ok && !public[name] {
+			t.Errorf("API %s has no policy", name)
+		}
+	}
+}
+
+func TestAPI_authorize(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		api      string
+		ownerID  int64
+		wantKind errors.Kind
+		wantErr  bool
+	}{
+		{
+			name:     "unauthenticated",
+			ctx:      context.Background(),
+			api:      "CreateUser",
+			wantKind: errors.KindUnauthorized,
+			wantErr:  true,
+		},
+		{
+			name: "required scope",
+			ctx:  withClaims("1", "users:write"),
+			api:  "CreateUser",
+		},
+		{
+			name:     "missing scope",
+			ctx:      withClaims("1", "users:read"),
+			api:      "CreateUser",
+			wantKind: errors.KindForbidden,
+			wantErr:  true,
+		},
+		{
+			name:    "owner",
+			ctx:     withClaims("7", "users:read", RoleUser),
+			api:     "ReadUserByID",
+			ownerID: 7,
+		},
+		{
+			name:     "not the owner",
+			ctx:      withClaims("8", "users:read", RoleUser),
+			api:      "ReadUserByID",
+			ownerID:  7,
+			wantKind: errors.KindForbidden,
+			wantErr:  true,
+		},
+		{
+			name:    "admin is not the owner",
+			ctx:     withClaims("8", "users:read", RoleAdmin),
+			api:     "ReadUserByID",
+			ownerID: 7,
+		},
+		{
+			name:     "owner without a role",
+			ctx:      withClaims("7", "users:read"),
+			api:      "ReadUserByID",
+			ownerID:  7,
+			wantKind: errors.KindForbidden,
+			wantErr:  true,
+		},
+		{
+			name:     "admin only",
+			ctx:      withClaims("7", "users:read", RoleUser),
+			api:      "ListUsers",
+			wantKind: errors.KindForbidden,
+			wantErr:  true,
+		},
+		{
+			name:     "API without a policy",
+			ctx:      withClaims("7", "users:read users:write openai", RoleAdmin),
+			api:      "DropEverything",
+			wantKind: errors.KindForbidden,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &API{}
+			err := a.authorize(tt.ctx, tt.api, tt.ownerID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && errors.KindOf(err) != tt.wantKind {
+				t.Errorf("authorize() error kind = %v, want %v", errors.KindOf(err), tt.wantKind)
+			}
+		})
+	}
+
+	t.Run("authorization disabled", func(t *testing.T) {
+		a := &API{authDisabled: true}
+		err := a.authorize(context.Background(), "ListUsers", 0)
+		if err != nil {
+			t.Errorf("authorize() error = %v", err)
+		}
+	})
+}
+
+func TestAPI_ReadUserByEmail(t *testing.T) {
+	ctx := context.Background()
+	us, _ := users.NewService(persistence.NewUserMemoryPersistence())
+	a, _ := NewService(&Config{}, us, proxy.NewFake(), nil, nil)
+
+	u, err := us.CreateUser(ctx, &domain.User{FirstName: "Jane", Email: "jane.doe@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	got, err := a.ReadUserByEmail(withClaims("1", "users:read", RoleUser), u.Email)
+	if err != nil {
+		t.Fatalf("ReadUserByEmail() of own user error = %v", err)
+	}
+	if got.ID != u.ID {
+		t.Errorf("got user %d, want %d", got.ID, u.ID)
+	}
+
+	// the callers not permitted get the same error for the emails in use & the unknown ones
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantKind errors.Kind
+	}{
+		{name: "unauthenticated", ctx: ctx, wantKind: errors.KindUnauthorized},
+		{name: "scope missing", ctx: withClaims("1", "openai", RoleUser), wantKind: errors.KindForbidden},
+		{name: "another user", ctx: withClaims("999", "users:read", RoleUser), wantKind: errors.KindNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errKnown := a.ReadUserByEmail(tt.ctx, u.Email)
+			_, errUnknown := a.ReadUserByEmail(tt.ctx, "john.doe@example.com")
+			if errors.KindOf(errKnown) != tt.wantKind || errors.KindOf(errUnknown) != tt.wantKind {
+				t.Fatalf("ReadUserByEmail() errors = %v, %v, want %v", errKnown, errUnknown, tt.wantKind)
+			}
+
+			_, known, _ := errors.HTTPStatusCodeMessage(errKnown)
+			_, unknown, _ := errors.HTTPStatusCodeMessage(errUnknown)
+			if known != unknown {
+				t.Errorf("ReadUserByEmail() messages = %q, %q, want the same", known, unknown)
+			}
+		})
+	}
+}
@@ -3,12 +3,33 @@ package api
 import (
 	"context"
 
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
 	"github.com/mohamedveron/go_app_template/internal/users/domain"
 )
 
 // CreateUser is the API to create/signup a new user
 func (a *API) CreateUser(ctx context.Context, u *domain.User) (*domain.User, error) {
-	u, err := a.users.CreateUser(ctx, u)
+	err := a.authorize(ctx, "CreateUser", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err = a.users.CreateUser(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// ReadUserByID is the API to read an existing user by their ID
+func (a *API) ReadUserByID(ctx context.Context, id int64) (*domain.User, error) {
+	err := a.authorize(ctx, "ReadUserByID", id)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := a.users.ReadByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -18,10 +39,77 @@ func (a *API) CreateUser(ctx context.Context, u *domain.User) (*domain.User, err
 
 // ReadUserByEmail is the API to read an existing user by their email
 func (a *API) ReadUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	// the owner is known only after reading the user, the rest of the policy is checked before so
+	// that the emails in use aren't revealed to the callers not permitted
+	claims, perm, err := a.permitted(ctx, "ReadUserByEmail")
+	if err != nil {
+		return nil, err
+	}
+
 	u, err := a.users.ReadByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 
+	// the user of someone else is not found, just like a missing one
+	if !ownerOrAdmin(claims, perm, u.ID) {
+		return nil, errors.NotFound("email not found")
+	}
+
+	return u, nil
+}
+
+// UpdateUser is the API to replace an existing user
+func (a *API) UpdateUser(ctx context.Context, u *domain.User) (*domain.User, error) {
+	err := a.authorize(ctx, "UpdateUser", u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err = a.users.UpdateUser(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
 	return u, nil
 }
+
+// PatchUser is the API to partially update an existing user
+func (a *API) PatchUser(ctx context.Context, id int64, version int64, patch *domain.UserPatch) (*domain.User, error) {
+	err := a.authorize(ctx, "PatchUser", id)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := a.users.PatchUser(ctx, id, version, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// DeleteUser is the API to delete an existing user
+func (a *API) DeleteUser(ctx context.Context, id int64) error {
+	err := a.authorize(ctx, "DeleteUser", id)
+	if err != nil {
+		return err
+	}
+
+	return a.users.DeleteUser(ctx, id)
+}
+
+// ListUsers is the API to list users page by page
+func (a *API) ListUsers(ctx context.Context, query *domain.UsersQuery, cursor string) (*domain.UsersPage, error) {
+	err := a.authorize(ctx, "ListUsers", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := a.users.ListUsers(ctx, query, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
@@ -3,12 +3,43 @@ package configs
 import (
 	"fmt"
 	"os"
-	"strconv"
 	"time"
 
 	"github.com/mohamedveron/go_app_template/cmd/server/http"
+	"github.com/mohamedveron/go_app_template/internal/pkg/auth"
+	"github.com/mohamedveron/go_app_template/internal/pkg/buildinfo"
+	"github.com/mohamedveron/go_app_template/internal/pkg/cache"
+	"github.com/mohamedveron/go_app_template/internal/pkg/circuitbreaker"
 	"github.com/mohamedveron/go_app_template/internal/pkg/datastore"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
 	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
+	"github.com/mohamedveron/go_app_template/internal/pkg/tracing"
+	"github.com/mohamedveron/go_app_template/proxy"
+)
+
+const (
+	// UsersStorePostgres stores users in Postgres
+	UsersStorePostgres = "postgres"
+	// UsersStoreMongoDB stores users in MongoDB
+	UsersStoreMongoDB = "mongodb"
+	// UsersStoreMemory stores users in memory, all users are lost when the app stops. Useful for
+	// demos & local development without any datastore
+	UsersStoreMemory = "memory"
+)
+
+const (
+	// LLMProviderOpenAI generates the completions with the OpenAI API
+	LLMProviderOpenAI = "openai"
+	// LLMProviderFake responds with the completions scripted in LLM_FAKE_FIXTURES, without calling
+	// any provider. Useful for tests & local development
+	LLMProviderFake = "fake"
+)
+
+const (
+	// envConfigFile is the env variable to provide path of the YAML config file
+	envConfigFile = "CONFIG_FILE"
+	// defaultConfigFile is read if available, when no config file is provided
+	defaultConfigFile = "config.yaml"
 )
 
 // Configs struct handles all dependencies required for handling configurations
@@ -17,58 +48,316 @@ type Configs struct {
 	Version              string `yaml:"version" env:"APP_VERSION" envDefault:"v0.0.0"`
 	ServiceAccountBase64 string `yaml:"serviceAccountBase64" env:"SERVICE_ACCOUNT_BASE64"`
 	Environment          string `yaml:"goenv" env:"GOENV"`
+	// UsersStore is the datastore used for persisting users, one of the UsersStore* values
+	UsersStore string `yaml:"usersStore" env:"USERS_STORE" envDefault:"postgres"`
+
+	Log struct {
+		// Level is the log level at startup, one of debug, info, warn or error. It can be changed
+		// at runtime with the /-/log/level endpoint
+		Level string `yaml:"level" env:"LOG_LEVEL" envDefault:"info"`
+		// RedactKeys are the keys of the logged fields whose values are masked
+		RedactKeys []string `yaml:"redactKeys" env:"LOG_REDACT_KEYS" envDefault:"password,email,mobile,authorization"`
+	} `yaml:"log"`
+
+	// Telemetry is the configuration of tracing
+	Telemetry struct {
+		// Exporter of the spans, none, stdout or otlp
+		Exporter     string `yaml:"exporter" env:"TRACING_EXPORTER" envDefault:"none"`
+		OTLPEndpoint string `yaml:"otlpEndpoint" env:"TRACING_OTLP_ENDPOINT" envDefault:"localhost:4318"`
+		OTLPInsecure bool   `yaml:"otlpInsecure" env:"TRACING_OTLP_INSECURE" envDefault:"true"`
+		// SampleRatio is the ratio of the traces sampled, from 0 to 1
+		SampleRatio float64 `yaml:"sampleRatio" env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+	} `yaml:"telemetry"`
+
+	HTTPServer struct {
+		Host string `yaml:"host" env:"HTTP_HOST"`
+		// PORT is not prefixed, since it's set by App Engine & most of the container platforms
+		Port              int           `yaml:"port" env:"PORT" envDefault:"9090"`
+		ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" env:"HTTP_READ_HEADER_TIMEOUT" envDefault:"5s"`
+		ReadTimeout       time.Duration `yaml:"readTimeout" env:"HTTP_READ_TIMEOUT" envDefault:"5s"`
+		WriteTimeout      time.Duration `yaml:"writeTimeout" env:"HTTP_WRITE_TIMEOUT" envDefault:"5s"`
+		IdleTimeout       time.Duration `yaml:"idleTimeout" env:"HTTP_IDLE_TIMEOUT" envDefault:"60s"`
+		// AuthDisabled disables authentication of all the APIs, only meant for local development
+		AuthDisabled           bool          `yaml:"authDisabled" env:"HTTP_AUTH_DISABLED" envDefault:"false"`
+		JwkURL                 string        `yaml:"jwkURL" env:"HTTP_JWK_URL"`
+		JwtIssuer              string        `yaml:"jwtIssuer" env:"HTTP_JWT_ISSUER"`
+		JwtAudience            string        `yaml:"jwtAudience" env:"HTTP_JWT_AUDIENCE"`
+		JwtLeeway              time.Duration `yaml:"jwtLeeway" env:"HTTP_JWT_LEEWAY" envDefault:"30s"`
+		JwksTTL                time.Duration `yaml:"jwksTTL" env:"HTTP_JWKS_TTL" envDefault:"1h"`
+		JwksMinRefreshInterval time.Duration `yaml:"jwksMinRefreshInterval" env:"HTTP_JWKS_MIN_REFRESH_INTERVAL" envDefault:"1m"`
+		AllowedOrigins         []string      `yaml:"allowedOrigins" env:"HTTP_ALLOWED_ORIGINS"`
+		ShutdownDrainDelay     time.Duration `yaml:"shutdownDrainDelay" env:"SHUTDOWN_DRAIN_DELAY" envDefault:"5s"`
+		ShutdownTimeout        time.Duration `yaml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT" envDefault:"15s"`
+		ReadinessTimeout       time.Duration `yaml:"readinessTimeout" env:"HTTP_READINESS_TIMEOUT" envDefault:"2s"`
+		StreamTimeout          time.Duration `yaml:"streamTimeout" env:"HTTP_STREAM_TIMEOUT" envDefault:"2m"`
+	} `yaml:"http"`
+
+	LLM struct {
+		// Provider of the completions, one of the LLMProvider* values
+		Provider string `yaml:"provider" env:"LLM_PROVIDER" envDefault:"openai"`
+		// FakeFixtures is the JSON file with the completions of the fake provider, the fixtures
+		// shipped along with the binary are used when it's empty
+		FakeFixtures string `yaml:"fakeFixtures" env:"LLM_FAKE_FIXTURES"`
+		// PromptsDir is the directory of the YAML prompt templates, the templates shipped along
+		// with the binary are used when it's empty
+		PromptsDir string `yaml:"promptsDir" env:"LLM_PROMPTS_DIR"`
+		// DefaultPrompt is the name of the prompt template used when none is asked for
+		DefaultPrompt string `yaml:"defaultPrompt" env:"LLM_DEFAULT_PROMPT" envDefault:"paragraph"`
+		// CacheBackend of the completions, none, memory, or postgres to share them by all the
+		// instances of the app
+		CacheBackend    string        `yaml:"cacheBackend" env:"LLM_CACHE_BACKEND" envDefault:"memory"`
+		CacheTTL        time.Duration `yaml:"cacheTTL" env:"LLM_CACHE_TTL" envDefault:"24h"`
+		CacheMaxEntries int           `yaml:"cacheMaxEntries" env:"LLM_CACHE_MAX_ENTRIES" envDefault:"1000"`
+	} `yaml:"llm"`
+
+	OpenAI struct {
+		APIKey string `yaml:"apiKey" env:"OPENAI_API_KEY"`
+		Model  string `yaml:"model" env:"OPENAI_MODEL" envDefault:"gpt-4"`
+		// BaseURL overrides the URL of the OpenAI API, e.g. for an OpenAI compatible provider
+		BaseURL string `yaml:"baseURL" env:"OPENAI_BASE_URL"`
+		// Timeout of every call to the OpenAI API, streams time out when no chunk is received
+		// within it
+		Timeout time.Duration `yaml:"timeout" env:"OPENAI_TIMEOUT" envDefault:"30s"`
+		// MaxRetries of the requests failed with 429, 5xx or a network error, 0 disables the retries
+		MaxRetries     int           `yaml:"maxRetries" env:"OPENAI_MAX_RETRIES" envDefault:"3"`
+		RetryBaseDelay time.Duration `yaml:"retryBaseDelay" env:"OPENAI_RETRY_BASE_DELAY" envDefault:"200ms"`
+		RetryMaxDelay  time.Duration `yaml:"retryMaxDelay" env:"OPENAI_RETRY_MAX_DELAY" envDefault:"5s"`
+		// RetryBudget is the total time the retries of a request can take, including the delays
+		RetryBudget time.Duration `yaml:"retryBudget" env:"OPENAI_RETRY_BUDGET" envDefault:"10s"`
+		// BreakerWindow is the number of the most recent requests, whose failure ratio opens the
+		// circuit breaker once it reaches BreakerFailureRatio, after BreakerMinRequests
+		BreakerWindow       int           `yaml:"breakerWindow" env:"OPENAI_BREAKER_WINDOW" envDefault:"20"`
+		BreakerMinRequests  int           `yaml:"breakerMinRequests" env:"OPENAI_BREAKER_MIN_REQUESTS" envDefault:"10"`
+		BreakerFailureRatio float64       `yaml:"breakerFailureRatio" env:"OPENAI_BREAKER_FAILURE_RATIO" envDefault:"0.5"`
+		BreakerOpenTimeout  time.Duration `yaml:"breakerOpenTimeout" env:"OPENAI_BREAKER_OPEN_TIMEOUT" envDefault:"30s"`
+		// ReadinessCheck adds the reachability of the OpenAI API to the readiness checks, as a
+		// non-critical check
+		ReadinessCheck bool `yaml:"readinessCheck" env:"OPENAI_READINESS_CHECK" envDefault:"false"`
+	} `yaml:"openai"`
+
+	// Postgres env variables are the same as the ones used by the official Postgres docker image
+	Postgres struct {
+		Host         string        `yaml:"host" env:"HOST" envDefault:"localhost"`
+		Port         string        `yaml:"port" env:"PORT" envDefault:"5432"`
+		Driver       string        `yaml:"driver" env:"DRIVER" envDefault:"postgres"`
+		StoreName    string        `yaml:"storeName" env:"DB" envDefault:"go_app"`
+		Username     string        `yaml:"username" env:"USER,required"`
+		Password     string        `yaml:"password" env:"PASSWORD"`
+		SSLMode      string        `yaml:"sslMode" env:"SSLMODE"`
+		ConnPoolSize uint          `yaml:"connPoolSize" env:"CONN_POOL_SIZE" envDefault:"10"`
+		ReadTimeout  time.Duration `yaml:"readTimeout" env:"READ_TIMEOUT" envDefault:"5s"`
+		WriteTimeout time.Duration `yaml:"writeTimeout" env:"WRITE_TIMEOUT" envDefault:"5s"`
+		IdleTimeout  time.Duration `yaml:"idleTimeout" env:"IDLE_TIMEOUT" envDefault:"60s"`
+		DialTimeout  time.Duration `yaml:"dialTimeout" env:"DIAL_TIMEOUT" envDefault:"10s"`
+		AutoMigrate  bool          `yaml:"autoMigrate" env:"AUTO_MIGRATE" envDefault:"false"`
+	} `yaml:"postgres" envPrefix:"POSTGRES_"`
 
 	MongoDB struct {
-		URI                    string        `env:"CONNECTION_STRING"`
-		PingTimeout            time.Duration `env:"PING_TIMEOUT" envDefault:"3s"`
-		ConnectTimeout         time.Duration `env:"CONNECT_TIMEOUT" envDefault:"5s"`
-		HeartbeatInterval      time.Duration `env:"HEARTBEAT_INTERVAL" envDefault:"10s"`
-		LocalThreshold         time.Duration `env:"LOCAL_THRESHOLD" envDefault:"15ms"`
-		MaxConnIdleTime        time.Duration `env:"MAX_CONN_IDLE_TIME" envDefault:"60s"`
-		MaxPoolSize            uint64        `env:"MAX_POOL_SIZE" envDefault:"100"`
-		MinPoolSize            uint64        `env:"MIN_POOL_SIZE" envDefault:"1"`
-		ServerSelectionTimeout time.Duration `env:"SERVER_SELECTION_TIMEOUT" envDefault:"30s"`
+		URI                    string        `yaml:"uri" env:"CONNECTION_STRING,required"`
+		PingTimeout            time.Duration `yaml:"pingTimeout" env:"PING_TIMEOUT" envDefault:"3s"`
+		ConnectTimeout         time.Duration `yaml:"connectTimeout" env:"CONNECT_TIMEOUT" envDefault:"5s"`
+		HeartbeatInterval      time.Duration `yaml:"heartbeatInterval" env:"HEARTBEAT_INTERVAL" envDefault:"10s"`
+		LocalThreshold         time.Duration `yaml:"localThreshold" env:"LOCAL_THRESHOLD" envDefault:"15ms"`
+		MaxConnIdleTime        time.Duration `yaml:"maxConnIdleTime" env:"MAX_CONN_IDLE_TIME" envDefault:"60s"`
+		MaxPoolSize            uint64        `yaml:"maxPoolSize" env:"MAX_POOL_SIZE" envDefault:"100"`
+		MinPoolSize            uint64        `yaml:"minPoolSize" env:"MIN_POOL_SIZE" envDefault:"1"`
+		ServerSelectionTimeout time.Duration `yaml:"serverSelectionTimeout" env:"SERVER_SELECTION_TIMEOUT" envDefault:"30s"`
+	} `yaml:"mongodb" envPrefix:"MONGODB_"`
+}
+
+// Logger returns the configuration of the global logger
+func (cfg *Configs) Logger() (*logger.Config, error) {
+	level, err := logger.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return nil, errors.ValidationErr(err, fmt.Sprintf("invalid LOG_LEVEL '%s'", cfg.Log.Level))
+	}
+
+	return &logger.Config{
+		Level:      level,
+		RedactKeys: cfg.Log.RedactKeys,
+	}, nil
+}
+
+// Tracing returns the configuration of tracing
+func (cfg *Configs) Tracing() (*tracing.Config, error) {
+	tcfg := cfg.Telemetry
+	switch tcfg.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		return nil, errors.Validation(fmt.Sprintf("invalid TRACING_EXPORTER '%s'", tcfg.Exporter))
+	}
+
+	if tcfg.SampleRatio < 0 || tcfg.SampleRatio > 1 {
+		return nil, errors.Validation("TRACING_SAMPLE_RATIO should be from 0 to 1")
 	}
+
+	return &tracing.Config{
+		ServiceName:    cfg.AppName,
+		ServiceVersion: buildinfo.Get().Version,
+		Exporter:       tcfg.Exporter,
+		OTLPEndpoint:   tcfg.OTLPEndpoint,
+		OTLPInsecure:   tcfg.OTLPInsecure,
+		SampleRatio:    tcfg.SampleRatio,
+	}, nil
 }
 
 // HTTP returns the configuration required for HTTP package
 func (cfg *Configs) HTTP() (*http.Config, error) {
-	envPort := os.Getenv("PORT")
-	if envPort == "" {
-		envPort = "9090"
+	err := validate(&cfg.HTTPServer, "")
+	if err != nil {
+		return nil, err
 	}
 
-	port, err := strconv.Atoi(envPort)
-	if err != nil {
-		logger.Error("wrong port provided", envPort)
+	hcfg := cfg.HTTPServer
+	if !hcfg.AuthDisabled && (hcfg.JwkURL == "" || hcfg.JwtIssuer == "" || hcfg.JwtAudience == "") {
+		return nil, errors.Validation(
+			"HTTP_JWK_URL, HTTP_JWT_ISSUER & HTTP_JWT_AUDIENCE are required, unless HTTP_AUTH_DISABLED is true",
+		)
 	}
 
 	return &http.Config{
-		Port:         port,
-		ReadTimeout:  time.Second * 5,
-		WriteTimeout: time.Second * 5,
-		//DialTimeout:       time.Second * 3,
+		Host:              hcfg.Host,
+		Port:              hcfg.Port,
+		Environment:       cfg.Environment,
+		ReadHeaderTimeout: hcfg.ReadHeaderTimeout,
+		ReadTimeout:       hcfg.ReadTimeout,
+		WriteTimeout:      hcfg.WriteTimeout,
+		IdleTimeout:       hcfg.IdleTimeout,
+		AuthDisabled:      hcfg.AuthDisabled,
+		Auth: auth.Config{
+			JwkURL:                 hcfg.JwkURL,
+			Issuer:                 hcfg.JwtIssuer,
+			Audience:               hcfg.JwtAudience,
+			KeysTTL:                hcfg.JwksTTL,
+			KeysMinRefreshInterval: hcfg.JwksMinRefreshInterval,
+			Leeway:                 hcfg.JwtLeeway,
+		},
+		AllowedOrigins:     hcfg.AllowedOrigins,
+		ShutdownDrainDelay: hcfg.ShutdownDrainDelay,
+		ShutdownTimeout:    hcfg.ShutdownTimeout,
+		ReadinessTimeout:   hcfg.ReadinessTimeout,
+		StreamTimeout:      hcfg.StreamTimeout,
+	}, nil
+}
+
+// OpenAIProxy returns the configuration of the OpenAI API client
+func (cfg *Configs) OpenAIProxy() (*proxy.OpenAIConfig, error) {
+	ocfg := cfg.OpenAI
+	if ocfg.APIKey == "" {
+		return nil, errors.Validation("OPENAI_API_KEY is required, unless LLM_PROVIDER is fake")
+	}
+
+	if ocfg.Model == "" {
+		return nil, errors.Validation("OPENAI_MODEL is required")
+	}
+
+	if ocfg.Timeout <= 0 {
+		return nil, errors.Validation("OPENAI_TIMEOUT should be greater than 0")
+	}
+
+	if ocfg.MaxRetries < 0 {
+		return nil, errors.Validation("OPENAI_MAX_RETRIES should not be negative")
+	}
+
+	if ocfg.BreakerWindow < 1 || ocfg.BreakerMinRequests > ocfg.BreakerWindow {
+		return nil, errors.Validation("OPENAI_BREAKER_WINDOW should be at least 1 & OPENAI_BREAKER_MIN_REQUESTS")
+	}
+
+	if ocfg.BreakerFailureRatio <= 0 || ocfg.BreakerFailureRatio > 1 {
+		return nil, errors.Validation("OPENAI_BREAKER_FAILURE_RATIO should be greater than 0, up to 1")
+	}
+
+	return &proxy.OpenAIConfig{
+		APIKey:  ocfg.APIKey,
+		Model:   ocfg.Model,
+		BaseURL: ocfg.BaseURL,
+		Timeout: ocfg.Timeout,
+		Retry: proxy.RetryConfig{
+			MaxRetries: ocfg.MaxRetries,
+			BaseDelay:  ocfg.RetryBaseDelay,
+			MaxDelay:   ocfg.RetryMaxDelay,
+			Budget:     ocfg.RetryBudget,
+		},
+		Breaker: circuitbreaker.Config{
+			Window:       ocfg.BreakerWindow,
+			MinCalls:     ocfg.BreakerMinRequests,
+			FailureRatio: ocfg.BreakerFailureRatio,
+			OpenTimeout:  ocfg.BreakerOpenTimeout,
+		},
+	}, nil
+}
+
+// LLMCache returns the configuration of the cache of the completions
+func (cfg *Configs) LLMCache() (*cache.Config, error) {
+	lcfg := cfg.LLM
+	switch lcfg.CacheBackend {
+	case cache.BackendNone, cache.BackendMemory, cache.BackendPostgres:
+	default:
+		return nil, errors.Validation(fmt.Sprintf("invalid LLM_CACHE_BACKEND '%s'", lcfg.CacheBackend))
+	}
+
+	if lcfg.CacheTTL <= 0 {
+		return nil, errors.Validation("LLM_CACHE_TTL should be greater than 0")
+	}
+
+	if lcfg.CacheMaxEntries < 1 {
+		return nil, errors.Validation("LLM_CACHE_MAX_ENTRIES should be at least 1")
+	}
+
+	return &cache.Config{
+		TTL:        lcfg.CacheTTL,
+		MaxEntries: lcfg.CacheMaxEntries,
 	}, nil
 }
 
 // Datastore returns datastore configuration
 func (cfg *Configs) Datastore() (*datastore.Config, error) {
+	err := validate(&cfg.Postgres, "POSTGRES_")
+	if err != nil {
+		return nil, err
+	}
+
+	pcfg := cfg.Postgres
 	return &datastore.Config{
-		Host:   "postgres",
-		Port:   "5432",
-		Driver: "postgres",
+		Host:   pcfg.Host,
+		Port:   pcfg.Port,
+		Driver: pcfg.Driver,
+
+		StoreName: pcfg.StoreName,
+		Username:  pcfg.Username,
+		Password:  pcfg.Password,
 
-		StoreName: "go_app",
-		Username:  "root",
-		Password:  "123321",
+		SSLMode: pcfg.SSLMode,
 
-		SSLMode: "",
+		ConnPoolSize: pcfg.ConnPoolSize,
+		ReadTimeout:  pcfg.ReadTimeout,
+		WriteTimeout: pcfg.WriteTimeout,
+		IdleTimeout:  pcfg.IdleTimeout,
+		DialTimeout:  pcfg.DialTimeout,
+
+		AutoMigrate: pcfg.AutoMigrate,
+	}, nil
+}
+
+// Mongo returns the MongoDB datastore configuration
+func (cfg *Configs) Mongo() (*datastore.MongoConfig, error) {
+	err := validate(&cfg.MongoDB, "MONGODB_")
+	if err != nil {
+		return nil, err
+	}
 
-		ConnPoolSize: 10,
-		ReadTimeout:  time.Second * 5,
-		WriteTimeout: time.Second * 5,
-		IdleTimeout:  time.Second * 60,
-		DialTimeout:  time.Second * 10,
+	mcfg := cfg.MongoDB
+	return &datastore.MongoConfig{
+		URI:                    mcfg.URI,
+		ClientID:               cfg.AppName,
+		PingTimeout:            mcfg.PingTimeout,
+		ConnectTimeout:         mcfg.ConnectTimeout,
+		HeartbeatInterval:      mcfg.HeartbeatInterval,
+		LocalThreshold:         mcfg.LocalThreshold,
+		MaxConnIdleTime:        mcfg.MaxConnIdleTime,
+		MaxPoolSize:            mcfg.MaxPoolSize,
+		MinPoolSize:            mcfg.MinPoolSize,
+		ServerSelectionTimeout: mcfg.ServerSelectionTimeout,
 	}, nil
 }
 
@@ -76,7 +365,25 @@ func (cfg *Configs) AppFullname() string {
 	return fmt.Sprintf("%s%s", cfg.AppName, cfg.Version)
 }
 
-// New returns an instance of Config with all the required dependencies initialized
+// New returns an instance of Config with all the required dependencies initialized. Configurations
+// are read from the YAML file set in CONFIG_FILE (config.yaml by default, if it exists), and are
+// overridden by env variables
 func New() (*Configs, error) {
-	return &Configs{}, nil
+	path, required := os.LookupEnv(envConfigFile)
+	if !required {
+		path = defaultConfigFile
+	}
+
+	cfg := &Configs{}
+	err := load(cfg, path, required)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validate(cfg, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
 }
@@ -0,0 +1,298 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	return path
+}
+
+func TestNew(t *testing.T) {
+	path := writeConfigFile(t, `
+appName: goapp
+http:
+  port: 8080
+  writeTimeout: 30s
+postgres:
+  host: postgres
+  username: root
+`)
+	t.Setenv(envConfigFile, path)
+	t.Setenv("PORT", "9999")
+	t.Setenv("HTTP_ALLOWED_ORIGINS", "https://a.example.com, https://b.example.com")
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+	t.Setenv("HTTP_AUTH_DISABLED", "true")
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	httpCfg, err := cfg.HTTP()
+	if err != nil {
+		t.Fatalf("HTTP() error = %v", err)
+	}
+	if httpCfg.Port != 9999 {
+		t.Errorf("env should override YAML, got port %d", httpCfg.Port)
+	}
+	if httpCfg.WriteTimeout != 30*time.Second {
+		t.Errorf("YAML should override defaults, got write timeout %s", httpCfg.WriteTimeout)
+	}
+	if httpCfg.ReadTimeout != 5*time.Second {
+		t.Errorf("expected default read timeout, got %s", httpCfg.ReadTimeout)
+	}
+	wantOrigins := []string{"https://a.example.com", "https://b.example.com"}
+	if !reflect.DeepEqual(httpCfg.AllowedOrigins, wantOrigins) {
+		t.Errorf("AllowedOrigins = %v, want %v", httpCfg.AllowedOrigins, wantOrigins)
+	}
+
+	dscfg, err := cfg.Datastore()
+	if err != nil {
+		t.Fatalf("Datastore() error = %v", err)
+	}
+	if dscfg.Host != "postgres" || dscfg.Username != "root" || dscfg.Password != "secret" {
+		t.Errorf("unexpected datastore config %+v", dscfg)
+	}
+	if dscfg.ConnPoolSize != 10 {
+		t.Errorf("expected default pool size, got %d", dscfg.ConnPoolSize)
+	}
+
+	if cfg.AppName != "goapp" {
+		t.Errorf("AppName = %s, want goapp", cfg.AppName)
+	}
+}
+
+func TestNew_Required(t *testing.T) {
+	t.Setenv(envConfigFile, writeConfigFile(t, "appName: goapp"))
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = cfg.Datastore()
+	if err == nil {
+		t.Errorf("expected error for missing POSTGRES_USER")
+	}
+
+	_, err = cfg.Mongo()
+	if err == nil {
+		t.Errorf("expected error for missing MONGODB_CONNECTION_STRING")
+	}
+}
+
+func TestNew_InvalidEnv(t *testing.T) {
+	t.Setenv(envConfigFile, writeConfigFile(t, ""))
+	t.Setenv("HTTP_READ_TIMEOUT", "five seconds")
+
+	_, err := New()
+	if err == nil {
+		t.Errorf("expected error for invalid duration")
+	}
+}
+
+func TestNew_MissingConfigFile(t *testing.T) {
+	t.Setenv(envConfigFile, filepath.Join(t.TempDir(), "missing.yaml"))
+
+	_, err := New()
+	if err == nil {
+		t.Errorf("expected error when the config file provided does not exist")
+	}
+}
+
+func TestConfigs_HTTP_Auth(t *testing.T) {
+	t.Setenv(envConfigFile, writeConfigFile(t, `
+http:
+  jwkURL: https://issuer.example.com/.well-known/jwks.json
+  jwtIssuer: https://issuer.example.com/
+`))
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = cfg.HTTP()
+	if err == nil {
+		t.Errorf("expected error for missing HTTP_JWT_AUDIENCE")
+	}
+
+	t.Setenv("HTTP_JWT_AUDIENCE", "go-app")
+	cfg, err = New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	httpCfg, err := cfg.HTTP()
+	if err != nil {
+		t.Fatalf("HTTP() error = %v", err)
+	}
+	if httpCfg.Auth.Audience != "go-app" || httpCfg.Auth.KeysTTL != time.Hour {
+		t.Errorf("unexpected auth config %+v", httpCfg.Auth)
+	}
+}
+
+func TestConfigs_Logger(t *testing.T) {
+	t.Setenv(envConfigFile, writeConfigFile(t, `
+log:
+  level: debug
+`))
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	logCfg, err := cfg.Logger()
+	if err != nil {
+		t.Fatalf("Logger() error = %v", err)
+	}
+	if logCfg.Level != logger.DebugLevel {
+		t.Errorf("Level = %v, want debug", logCfg.Level)
+	}
+	if !reflect.DeepEqual(logCfg.RedactKeys, logger.DefaultRedactKeys) {
+		t.Errorf("RedactKeys = %v, want %v", logCfg.RedactKeys, logger.DefaultRedactKeys)
+	}
+
+	t.Setenv("LOG_LEVEL", "verbose")
+	cfg, err = New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = cfg.Logger()
+	if errors.KindOf(err) != errors.KindValidation {
+		t.Errorf("Logger() error = %v, want a validation error", err)
+	}
+}
+
+func TestConfigs_Tracing(t *testing.T) {
+	t.Setenv(envConfigFile, writeConfigFile(t, `
+appName: goapp
+telemetry:
+  exporter: otlp
+  sampleRatio: 0.5
+`))
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tracingCfg, err := cfg.Tracing()
+	if err != nil {
+		t.Fatalf("Tracing() error = %v", err)
+	}
+	if tracingCfg.Exporter != "otlp" || tracingCfg.SampleRatio != 0.5 || tracingCfg.OTLPEndpoint != "localhost:4318" {
+		t.Errorf("unexpected tracing config %+v", tracingCfg)
+	}
+	if tracingCfg.ServiceName != "goapp" {
+		t.Errorf("ServiceName = %s, want goapp", tracingCfg.ServiceName)
+	}
+
+	t.Setenv("TRACING_EXPORTER", "jaeger")
+	cfg, err = New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = cfg.Tracing()
+	if errors.KindOf(err) != errors.KindValidation {
+		t.Errorf("Tracing() error = %v, want a validation error", err)
+	}
+}
+
+func TestConfigs_OpenAIProxy(t *testing.T) {
+	t.Setenv(envConfigFile, writeConfigFile(t, `
+openai:
+  model: gpt-4o
+`))
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = cfg.OpenAIProxy()
+	if errors.KindOf(err) != errors.KindValidation {
+		t.Errorf("OpenAIProxy() error = %v, want a validation error without an API key", err)
+	}
+
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	cfg, err = New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	openAICfg, err := cfg.OpenAIProxy()
+	if err != nil {
+		t.Fatalf("OpenAIProxy() error = %v", err)
+	}
+	if openAICfg.APIKey != "sk-test" || openAICfg.Model != "gpt-4o" || openAICfg.Timeout != 30*time.Second {
+		t.Errorf("unexpected OpenAI config %+v", openAICfg)
+	}
+	if openAICfg.Retry.MaxRetries != 3 || openAICfg.Retry.Budget != 10*time.Second {
+		t.Errorf("unexpected OpenAI retry config %+v", openAICfg.Retry)
+	}
+	if openAICfg.Breaker.FailureRatio != 0.5 || openAICfg.Breaker.Window != 20 {
+		t.Errorf("unexpected OpenAI circuit breaker config %+v", openAICfg.Breaker)
+	}
+
+	t.Setenv("OPENAI_BREAKER_FAILURE_RATIO", "0")
+	cfg, err = New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = cfg.OpenAIProxy()
+	if errors.KindOf(err) != errors.KindValidation {
+		t.Errorf("OpenAIProxy() error = %v, want a validation error", err)
+	}
+}
+
+func TestConfigs_LLMCache(t *testing.T) {
+	t.Setenv(envConfigFile, writeConfigFile(t, `
+llm:
+  cacheBackend: postgres
+  cacheTTL: 1h
+`))
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	cacheCfg, err := cfg.LLMCache()
+	if err != nil {
+		t.Fatalf("LLMCache() error = %v", err)
+	}
+	if cacheCfg.TTL != time.Hour || cacheCfg.MaxEntries != 1000 {
+		t.Errorf("unexpected cache config %+v", cacheCfg)
+	}
+
+	t.Setenv("LLM_CACHE_BACKEND", "redis")
+	cfg, err = New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = cfg.LLMCache()
+	if errors.KindOf(err) != errors.KindValidation {
+		t.Errorf("LLMCache() error = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,220 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tagEnv        = "env"
+	tagEnvDefault = "envDefault"
+	tagEnvPrefix  = "envPrefix"
+	optRequired   = "required"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// load populates cfg in the following order of precedence (lowest to highest)
+// 1. the default values as per the `envDefault` tags
+// 2. the YAML file at path, if it exists. If required is false, a missing file is ignored
+// 3. the environment variables as per the `env` & `envPrefix` tags
+func load(cfg interface{}, path string, required bool) error {
+	rv := reflect.ValueOf(cfg).Elem()
+
+	err := walk(rv, "", applyDefault)
+	if err != nil {
+		return err
+	}
+
+	err = loadYAML(cfg, path, required)
+	if err != nil {
+		return err
+	}
+
+	return walk(rv, "", applyEnv)
+}
+
+func loadYAML(cfg interface{}, path string, required bool) error {
+	if path == "" {
+		return nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return nil
+		}
+		return errors.InternalErr(err, fmt.Sprintf("failed to read config file '%s'", path))
+	}
+
+	err = yaml.Unmarshal(raw, cfg)
+	if err != nil {
+		return errors.ValidationErr(err, fmt.Sprintf("invalid config file '%s'", path))
+	}
+
+	return nil
+}
+
+// walk calls fn for every field which has an env tag, recursing into nested structs. Prefixes of
+// nested structs are accumulated & passed on
+func walk(rv reflect.Value, prefix string, fn func(field reflect.Value, sf reflect.StructField, prefix string) error) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		field := rv.Field(i)
+		if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+			err := walk(field, prefix+sf.Tag.Get(tagEnvPrefix), fn)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, ok := sf.Tag.Lookup(tagEnv); !ok {
+			continue
+		}
+
+		err := fn(field, sf, prefix)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyDefault(field reflect.Value, sf reflect.StructField, prefix string) error {
+	value, ok := sf.Tag.Lookup(tagEnvDefault)
+	if !ok {
+		return nil
+	}
+
+	err := setValue(field, value)
+	if err != nil {
+		return errors.InternalErr(err, fmt.Sprintf("invalid default value for %s", envName(sf, prefix)))
+	}
+
+	return nil
+}
+
+func applyEnv(field reflect.Value, sf reflect.StructField, prefix string) error {
+	name := envName(sf, prefix)
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+
+	err := setValue(field, value)
+	if err != nil {
+		return errors.ValidationErr(err, fmt.Sprintf("invalid value '%s' for %s", value, name))
+	}
+
+	return nil
+}
+
+// validate checks if all the fields of the given struct, marked as required, have a value. Nested
+// structs are not validated, so that each block can be validated only when it's used
+func validate(cfg interface{}, prefix string) error {
+	rv := reflect.Indirect(reflect.ValueOf(cfg))
+	rt := rv.Type()
+
+	missing := make([]string, 0)
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !isRequired(sf) {
+			continue
+		}
+		if rv.Field(i).IsZero() {
+			missing = append(missing, envName(sf, prefix))
+		}
+	}
+
+	if len(missing) > 0 {
+		return errors.Validation(fmt.Sprintf("missing required configuration: %s", strings.Join(missing, ", ")))
+	}
+
+	return nil
+}
+
+func envName(sf reflect.StructField, prefix string) string {
+	name, _, _ := strings.Cut(sf.Tag.Get(tagEnv), ",")
+	return prefix + name
+}
+
+func isRequired(sf reflect.StructField) bool {
+	_, opts, _ := strings.Cut(sf.Tag.Get(tagEnv), ",")
+	for _, opt := range strings.Split(opts, ",") {
+		if strings.TrimSpace(opt) == optRequired {
+			return true
+		}
+	}
+
+	return false
+}
+
+func setValue(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", field.Type())
+		}
+		parts := make([]string, 0)
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+		field.Set(reflect.ValueOf(parts))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
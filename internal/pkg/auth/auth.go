@@ -0,0 +1,106 @@
+// Package auth verifies the JWTs issued by an identity provider, which publishes its signing keys
+// as a JWKS (JSON Web Key Set).
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+)
+
+type claimsCtxKey struct{}
+
+// signingMethods are the algorithms accepted for signing the tokens, symmetric algorithms & 'none'
+// are never accepted
+var signingMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+}
+
+type Config struct {
+	// JwkURL is the URL of the JWKS of the identity provider
+	JwkURL string
+	// Issuer is the expected 'iss' claim of the tokens
+	Issuer string
+	// Audience is the expected 'aud' claim of the tokens
+	Audience string
+	// KeysTTL is how long the keys are cached, before fetching them again
+	KeysTTL time.Duration
+	// KeysMinRefreshInterval is the minimum interval between fetching the keys, when a token is
+	// signed with an unknown key
+	KeysMinRefreshInterval time.Duration
+	// Leeway is the allowed clock skew while validating the time based claims
+	Leeway time.Duration
+}
+
+// Claims are the verified claims of a token
+type Claims struct {
+	jwt.RegisteredClaims
+	// Scope is the space separated list of scopes granted to the token
+	Scope string `json:"scope,omitempty"`
+	// Roles are the roles of the subject
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Scopes returns the list of scopes granted to the token
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// Verifier verifies the signature & claims of tokens
+type Verifier struct {
+	keys   *KeySet
+	parser *jwt.Parser
+}
+
+// Verify verifies the signature, expiry, issuer & audience of the token, and returns its claims
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	claims := new(Claims)
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		// failing to fetch the keys is not the fault of the token
+		keyErr := new(errors.Error)
+		if errors.As(err, &keyErr) && keyErr.Kind() == errors.KindInternal {
+			return nil, err
+		}
+		return nil, errors.UnauthorizedErr(err, "invalid token")
+	}
+
+	return claims, nil
+}
+
+// NewVerifier returns a Verifier of the tokens issued by the configured identity provider
+func NewVerifier(cfg *Config) (*Verifier, error) {
+	if cfg.JwkURL == "" || cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.Validation("JWK URL, issuer & audience are required to verify tokens")
+	}
+
+	return &Verifier{
+		keys: NewKeySet(cfg.JwkURL, cfg.KeysTTL, cfg.KeysMinRefreshInterval),
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(signingMethods),
+			jwt.WithIssuer(cfg.Issuer),
+			jwt.WithAudience(cfg.Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(cfg.Leeway),
+		),
+	}, nil
+}
+
+// WithClaims returns a copy of ctx with the verified claims
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsCtxKey{}, claims)
+}
+
+// ClaimsFromContext returns the verified claims in ctx, if any
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsCtxKey{}).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,426 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com/"
+	testAudience = "go-app"
+)
+
+// testJWKS is a JWKS server, whose keys can be rotated while it's running
+type testJWKS struct {
+	lock    sync.Mutex
+	keys    []jwk
+	fetches int32
+	server  *httptest.Server
+}
+
+func (tj *testJWKS) setKeys(keys ...jwk) {
+	tj.lock.Lock()
+	tj.keys = keys
+	tj.lock.Unlock()
+}
+
+func (tj *testJWKS) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	atomic.AddInt32(&tj.fetches, 1)
+	tj.lock.Lock()
+	defer tj.lock.Unlock()
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": tj.keys})
+}
+
+func newTestJWKS(t *testing.T, keys ...jwk) *testJWKS {
+	t.Helper()
+
+	tj := &testJWKS{keys: keys}
+	tj.server = httptest.NewServer(tj)
+	t.Cleanup(tj.server.Close)
+
+	return tj
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func rsaJWK(t *testing.T, kid string) (*rsa.PrivateKey, jwk) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+
+	return key, jwk{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   encodeBigInt(key.N),
+		E:   encodeBigInt(big.NewInt(int64(key.E))),
+	}
+}
+
+func ecJWK(t *testing.T, kid string) (*ecdsa.PrivateKey, jwk) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+
+	return key, jwk{
+		Kty: "EC",
+		Kid: kid,
+		Crv: "P-256",
+		X:   encodeBigInt(key.X),
+		Y:   encodeBigInt(key.Y),
+	}
+}
+
+func validClaims() *Claims {
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "42",
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		Scope: "users:read users:write",
+		Roles: []string{"admin"},
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims *Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	return signed
+}
+
+func newTestVerifier(t *testing.T, url string, minRefreshInterval time.Duration) *Verifier {
+	t.Helper()
+
+	v, err := NewVerifier(&Config{
+		JwkURL:                 url,
+		Issuer:                 testIssuer,
+		Audience:               testAudience,
+		KeysTTL:                time.Hour,
+		KeysMinRefreshInterval: minRefreshInterval,
+	})
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	return v
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	rsaKey, rsaPub := rsaJWK(t, "rsa-1")
+	ecKey, ecPub := ecJWK(t, "ec-1")
+	otherKey, _ := rsaJWK(t, "rsa-1")
+	jwks := newTestJWKS(t, rsaPub, ecPub)
+	v := newTestVerifier(t, jwks.server.URL, time.Minute)
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr bool
+	}{
+		{
+			name: "valid RSA",
+			token: func() string {
+				return sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", validClaims())
+			},
+		},
+		{
+			name: "valid EC",
+			token: func() string {
+				return sign(t, jwt.SigningMethodES256, ecKey, "ec-1", validClaims())
+			},
+		},
+		{
+			name: "signed with another key",
+			token: func() string {
+				return sign(t, jwt.SigningMethodRS256, otherKey, "rsa-1", validClaims())
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			token: func() string {
+				c := validClaims()
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", c)
+			},
+			wantErr: true,
+		},
+		{
+			name: "without expiry",
+			token: func() string {
+				c := validClaims()
+				c.ExpiresAt = nil
+				return sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", c)
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				c := validClaims()
+				c.Issuer = "https://evil.example.com/"
+				return sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", c)
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				c := validClaims()
+				c.Audience = jwt.ClaimStrings{"another-app"}
+				return sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", c)
+			},
+			wantErr: true,
+		},
+		{
+			name: "symmetric algorithm",
+			token: func() string {
+				return sign(t, jwt.SigningMethodHS256, []byte("secret"), "rsa-1", validClaims())
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsigned",
+			token: func() string {
+				return sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "rsa-1", validClaims())
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed",
+			token: func() string {
+				return "not.a.token"
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(context.Background(), tt.token())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if errors.KindOf(err) != errors.KindUnauthorized {
+					t.Errorf("Verify() error kind = %v, want unauthorized", errors.KindOf(err))
+				}
+				return
+			}
+
+			if claims.Subject != "42" || len(claims.Scopes()) != 2 || claims.Roles[0] != "admin" {
+				t.Errorf("unexpected claims %+v", claims)
+			}
+		})
+	}
+}
+
+func TestVerifier_KeyRotation(t *testing.T) {
+	oldKey, oldPub := rsaJWK(t, "old")
+	newKey, newPub := rsaJWK(t, "new")
+	jwks := newTestJWKS(t, oldPub)
+	ctx := context.Background()
+
+	v := newTestVerifier(t, jwks.server.URL, 0)
+	_, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, oldKey, "old", validClaims()))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// known keys are served from the cache
+	_, err = v.Verify(ctx, sign(t, jwt.SigningMethodRS256, oldKey, "old", validClaims()))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if fetches := atomic.LoadInt32(&jwks.fetches); fetches != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", fetches)
+	}
+
+	jwks.setKeys(newPub)
+	_, err = v.Verify(ctx, sign(t, jwt.SigningMethodRS256, newKey, "new", validClaims()))
+	if err != nil {
+		t.Fatalf("Verify() with a rotated key error = %v", err)
+	}
+	if fetches := atomic.LoadInt32(&jwks.fetches); fetches != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", fetches)
+	}
+
+	_, err = v.Verify(ctx, sign(t, jwt.SigningMethodRS256, oldKey, "old", validClaims()))
+	if errors.KindOf(err) != errors.KindUnauthorized {
+		t.Errorf("Verify() with a removed key error = %v, want unauthorized", err)
+	}
+}
+
+func TestVerifier_UnknownKeyRateLimited(t *testing.T) {
+	key, pub := rsaJWK(t, "known")
+	jwks := newTestJWKS(t, pub)
+	ctx := context.Background()
+
+	v := newTestVerifier(t, jwks.server.URL, time.Hour)
+	for i := 0; i < 5; i++ {
+		_, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, key, "made-up", validClaims()))
+		if errors.KindOf(err) != errors.KindUnauthorized {
+			t.Fatalf("Verify() error = %v, want unauthorized", err)
+		}
+	}
+
+	if fetches := atomic.LoadInt32(&jwks.fetches); fetches != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", fetches)
+	}
+}
+
+func TestVerifier_JWKSUnavailable(t *testing.T) {
+	key, _ := rsaJWK(t, "rsa-1")
+	fetches := int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	v := newTestVerifier(t, server.URL, time.Minute)
+	for i := 0; i < 5; i++ {
+		_, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, key, "rsa-1", validClaims()))
+		if errors.KindOf(err) != errors.KindInternal {
+			t.Fatalf("Verify() error = %v, want internal error", err)
+		}
+	}
+
+	// the fetches are rate limited even though no key was ever fetched
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", got)
+	}
+}
+
+func TestVerifier_RefreshNotCancelledWithRequest(t *testing.T) {
+	oldKey, oldPub := rsaJWK(t, "old")
+	newKey, newPub := rsaJWK(t, "new")
+	jwks := newTestJWKS(t, oldPub)
+
+	const minRefreshInterval = time.Millisecond * 100
+	v := newTestVerifier(t, jwks.server.URL, minRefreshInterval)
+	_, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, oldKey, "old", validClaims()))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// the client of the request with the rotated key has disconnected, the fetch is still done, as
+	// a failed one would hold off the next fetches for minRefreshInterval
+	jwks.setKeys(oldPub, newPub)
+	time.Sleep(minRefreshInterval)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _ = v.Verify(ctx, sign(t, jwt.SigningMethodRS256, newKey, "new", validClaims()))
+
+	_, err = v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, newKey, "new", validClaims()))
+	if err != nil {
+		t.Errorf("Verify() with the rotated key error = %v", err)
+	}
+	if fetches := atomic.LoadInt32(&jwks.fetches); fetches != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", fetches)
+	}
+}
+
+func TestVerifier_JWKSTooLarge(t *testing.T) {
+	key, pub := rsaJWK(t, "rsa-1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys":    []jwk{pub},
+			"padding": strings.Repeat("x", maxJWKSSize),
+		})
+	}))
+	defer server.Close()
+
+	v := newTestVerifier(t, server.URL, time.Minute)
+	_, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, key, "rsa-1", validClaims()))
+	if errors.KindOf(err) != errors.KindInternal {
+		t.Errorf("Verify() error = %v, want internal error", err)
+	}
+}
+
+func TestVerifier_CachedKeyNotBlocked(t *testing.T) {
+	key, pub := rsaJWK(t, "known")
+	jwks := newTestJWKS(t, pub)
+	ctx := context.Background()
+
+	v := newTestVerifier(t, jwks.server.URL, 0)
+	_, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, key, "known", validClaims()))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// the JWKS server hangs while its lock is held, so the fetch for the unknown key is pending
+	jwks.lock.Lock()
+	pending := make(chan struct{})
+	go func() {
+		defer close(pending)
+		_, _ = v.Verify(ctx, sign(t, jwt.SigningMethodRS256, key, "made-up", validClaims()))
+	}()
+	for atomic.LoadInt32(&jwks.fetches) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	verified := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, key, "known", validClaims()))
+		verified <- err
+	}()
+	select {
+	case err = <-verified:
+		if err != nil {
+			t.Errorf("Verify() with a cached key error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Verify() with a cached key waited for the pending fetch")
+	}
+
+	jwks.lock.Unlock()
+	<-pending
+}
+
+func TestClaimsFromContext(t *testing.T) {
+	_, ok := ClaimsFromContext(context.Background())
+	if ok {
+		t.Errorf("expected no claims in an empty context")
+	}
+
+	claims := validClaims()
+	got, ok := ClaimsFromContext(WithClaims(context.Background(), claims))
+	if !ok || got != claims {
+		t.Errorf("ClaimsFromContext() = %v, %v, want %v", got, ok, claims)
+	}
+}
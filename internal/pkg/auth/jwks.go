@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+)
+
+const (
+	// fetchTimeout is the timeout of fetching the keys, which is independent of the request which
+	// triggered the fetch
+	fetchTimeout = time.Second * 10
+	// maxJWKSSize is the limit of the size of the JWKS read, in bytes
+	maxJWKSSize = 1 << 20
+)
+
+// jwk is a single JSON Web Key (RFC 7517), only the fields required for RSA & EC public keys
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// KeySet is a cache of the signing keys published as a JWKS. Keys are fetched on first use, when
+// the cache is older than its TTL, and when a token is signed with an unknown key ID, so that keys
+// rotated by the identity provider are picked up without a restart
+type KeySet struct {
+	url    string
+	client *http.Client
+	// ttl is how long the fetched keys are used, before fetching them again
+	ttl time.Duration
+	// minRefreshInterval limits how often keys are fetched, so that tokens with made up key IDs or
+	// an unavailable identity provider cannot have every request fetch them
+	minRefreshInterval time.Duration
+
+	// refreshLock is held while fetching the keys, so that there's a single fetch at a time. lock
+	// guards the fields below, it's never held while fetching so that the requests with a cached
+	// key don't wait for a fetch
+	refreshLock *sync.Mutex
+	lock        *sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	// fetchErr is the failure of the last fetch, it's nil once the keys are fetched
+	fetchErr error
+}
+
+// Key returns the public key with the given key ID. Cached keys are returned right away, even
+// when the cache is older than its TTL, in which case the keys are refreshed in the background.
+// Keys are fetched with a context of their own, since a fetch cancelled with the request would
+// still hold off the next fetches for minRefreshInterval
+func (ks *KeySet) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	key, known := ks.cached(kid)
+	if known {
+		if ks.stale() {
+			go ks.refreshStale()
+		}
+		return key, nil
+	}
+
+	ks.refreshLock.Lock()
+	defer ks.refreshLock.Unlock()
+
+	// the key could have been fetched by another request, while waiting for the lock
+	key, known = ks.cached(kid)
+	if known {
+		return key, nil
+	}
+
+	if ks.canRefresh() {
+		ks.refresh()
+	}
+
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+
+	key, ok := ks.keys[kid]
+	if ok {
+		return key, nil
+	}
+
+	// the last fetched keys are still used if they cannot be refreshed, so that the identity
+	// provider being unavailable does not fail all the requests
+	if ks.keys == nil && ks.fetchErr != nil {
+		return nil, ks.fetchErr
+	}
+
+	return nil, errors.Unauthorized(fmt.Sprintf("unknown signing key '%s'", kid))
+}
+
+// cached returns the cached key with the given key ID, if any
+func (ks *KeySet) cached(kid string) (crypto.PublicKey, bool) {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// stale reports whether the cached keys are older than the TTL
+func (ks *KeySet) stale() bool {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+
+	return time.Since(ks.fetchedAt) > ks.ttl
+}
+
+// canRefresh reports whether minRefreshInterval has passed since the last fetch
+func (ks *KeySet) canRefresh() bool {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+
+	return time.Since(ks.attemptedAt) >= ks.minRefreshInterval
+}
+
+// refreshStale refreshes the keys older than the TTL, unless they're already being fetched
+func (ks *KeySet) refreshStale() {
+	if !ks.refreshLock.TryLock() {
+		return
+	}
+	defer ks.refreshLock.Unlock()
+
+	if ks.stale() && ks.canRefresh() {
+		ks.refresh()
+	}
+}
+
+// refresh fetches the keys from the JWKS URL & replaces the cached ones, refreshLock is expected to
+// be held by the caller. The keys are kept when the fetch fails
+func (ks *KeySet) refresh() {
+	ks.lock.Lock()
+	ks.attemptedAt = time.Now()
+	ks.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	keys, err := ks.fetch(ctx)
+
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	ks.fetchErr = err
+	if err != nil {
+		return
+	}
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+}
+
+// fetch returns the keys published at the JWKS URL
+func (ks *KeySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.url, nil)
+	if err != nil {
+		return nil, errors.InternalErr(err, "failed to create JWKS request")
+	}
+
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, errors.InternalErr(err, "failed to fetch JWKS")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Internal(fmt.Sprintf("failed to fetch JWKS, got status %d", resp.StatusCode))
+	}
+
+	payload := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&payload)
+	if err != nil {
+		return nil, errors.InternalErr(err, "failed to decode JWKS")
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(payload.Keys))
+	for _, k := range payload.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			// a single unsupported key should not invalidate all the others
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	return keys, nil
+}
+
+// NewKeySet returns a KeySet of the JWKS published at the given URL
+func NewKeySet(url string, ttl time.Duration, minRefreshInterval time.Duration) *KeySet {
+	return &KeySet{
+		url:                url,
+		client:             &http.Client{Timeout: fetchTimeout},
+		ttl:                ttl,
+		minRefreshInterval: minRefreshInterval,
+		refreshLock:        &sync.Mutex{},
+		lock:               &sync.RWMutex{},
+	}
+}
@@ -0,0 +1,92 @@
+// Package buildinfo has the metadata of the build of the app. The values are set with ldflags
+// when building, e.g.
+//
+//	go build -ldflags "-X github.com/mohamedveron/go_app_template/internal/pkg/buildinfo.version=v1.2.0" ./cmd
+//
+// & the ones not set are read from the VCS info stamped by the Go toolchain, if available.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strconv"
+)
+
+// set with ldflags
+var (
+	version   string
+	commit    string
+	buildTime string
+	dirty     string
+)
+
+// readBuildInfo is replaced in tests
+var readBuildInfo = debug.ReadBuildInfo
+
+// Info is the metadata of the build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	// CommitTime is the time of the commit, from the VCS info. It's not the build time, which is
+	// only known when set with ldflags
+	CommitTime string `json:"commitTime"`
+	GoVersion  string `json:"goVersion"`
+	// Dirty is set if the build had uncommitted changes
+	Dirty bool `json:"dirty"`
+}
+
+// Get returns the metadata of the build. Values set with ldflags take precedence over the VCS info
+// of the Go toolchain
+func Get() Info {
+	info := Info{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+	}
+	info.Dirty, _ = strconv.ParseBool(dirty)
+
+	bi, ok := readBuildInfo()
+	if !ok {
+		return withDefaults(info)
+	}
+
+	if info.Version == "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			info.CommitTime = setting.Value
+		case "vcs.modified":
+			if dirty == "" {
+				info.Dirty = setting.Value == "true"
+			}
+		}
+	}
+
+	return withDefaults(info)
+}
+
+func withDefaults(info Info) Info {
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	if info.CommitTime == "" {
+		info.CommitTime = "unknown"
+	}
+
+	return info
+}
@@ -0,0 +1,75 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	defer func(prev func() (*debug.BuildInfo, bool)) {
+		readBuildInfo = prev
+		version, commit, buildTime, dirty = "", "", "", ""
+	}(readBuildInfo)
+
+	vcs := &debug.BuildInfo{
+		Main: debug.Module{Version: "(devel)"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0c1f1b2a3d4e"},
+			{Key: "vcs.time", Value: "2023-08-01T10:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		ldflags   [4]string
+		buildInfo *debug.BuildInfo
+		want      Info
+	}{
+		{
+			name: "no build info",
+			want: Info{Version: "dev", Commit: "unknown", BuildTime: "unknown", CommitTime: "unknown"},
+		},
+		{
+			name:      "VCS info",
+			buildInfo: vcs,
+			want: Info{
+				Version:    "dev",
+				Commit:     "0c1f1b2a3d4e",
+				BuildTime:  "unknown",
+				CommitTime: "2023-08-01T10:00:00Z",
+				Dirty:      true,
+			},
+		},
+		{
+			name:      "ldflags take precedence",
+			ldflags:   [4]string{"v1.2.0", "9a8b7c", "2023-08-02T10:00:00Z", "false"},
+			buildInfo: vcs,
+			want: Info{
+				Version:    "v1.2.0",
+				Commit:     "9a8b7c",
+				BuildTime:  "2023-08-02T10:00:00Z",
+				CommitTime: "2023-08-01T10:00:00Z",
+			},
+		},
+		{
+			name:      "module version",
+			buildInfo: &debug.BuildInfo{Main: debug.Module{Version: "v1.3.0"}},
+			want:      Info{Version: "v1.3.0", Commit: "unknown", BuildTime: "unknown", CommitTime: "unknown"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, commit, buildTime, dirty = tt.ldflags[0], tt.ldflags[1], tt.ldflags[2], tt.ldflags[3]
+			readBuildInfo = func() (*debug.BuildInfo, bool) {
+				return tt.buildInfo, tt.buildInfo != nil
+			}
+
+			tt.want.GoVersion = runtime.Version()
+			if got := Get(); got != tt.want {
+				t.Errorf("Get() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+// Package cache stores values by key for a limited time, in process or in a datastore shared by
+// all the instances of the app. Once a cache is full, the least recently used values are evicted.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// The backends of a cache
+const (
+	// BackendNone disables the cache
+	BackendNone = "none"
+	// BackendMemory caches in process, the values are not shared by the instances of the app
+	BackendMemory = "memory"
+	// BackendPostgres caches in Postgres, the values are shared by all the instances of the app
+	BackendPostgres = "postgres"
+)
+
+// Cache stores values by key
+type Cache interface {
+	// Get returns the value of the key, ok is false if it's not cached or is expired
+	Get(ctx context.Context, key string) (value string, ok bool, err error)
+	// Set caches the value of the key, replacing any existing value
+	Set(ctx context.Context, key string, value string) error
+}
+
+// Config is the configuration of a cache
+type Config struct {
+	// TTL is how long the values are cached
+	TTL time.Duration
+	// MaxEntries is the maximum number of values cached, the least recently used ones are evicted
+	// beyond it
+	MaxEntries int
+}
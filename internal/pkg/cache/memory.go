@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// LRU is an in-process Cache, which evicts the least recently used values once it's full
+type LRU struct {
+	cfg  Config
+	lock *sync.Mutex
+	now  func() time.Time
+	// order has the entries from the most to the least recently used
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// Get returns the value of the key, & marks it as the most recently used
+func (c *LRU) Get(_ context.Context, key string) (string, bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return "", false, nil
+	}
+
+	entry := elem.Value.(*lruEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return "", false, nil
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+// Set caches the value of the key, evicting the least recently used value if the cache is full
+func (c *LRU) Set(_ context.Context, key string, value string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	expiresAt := c.now().Add(c.cfg.TTL)
+	elem, ok := c.entries[key]
+	if ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.cfg.MaxEntries {
+		c.remove(c.order.Back())
+	}
+
+	return nil
+}
+
+func (c *LRU) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).key)
+}
+
+// NewLRU returns an empty in-process cache
+func NewLRU(cfg *Config) *LRU {
+	return &LRU{
+		cfg:     *cfg,
+		lock:    &sync.Mutex{},
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
+)
+
+// pruneEvery is the number of values set, after which the expired & the evicted values are deleted
+const pruneEvery = 100
+
+// Postgres is a Cache stored in Postgres, shared by all the instances of the app. The values are
+// stored in the LLMCache table, created by the migrations
+type Postgres struct {
+	cfg      Config
+	pqdriver *pgxpool.Pool
+	sets     *atomic.Int64
+}
+
+// Get returns the value of the key, & marks it as the most recently used
+func (c *Postgres) Get(ctx context.Context, key string) (string, bool, error) {
+	value := ""
+	err := c.pqdriver.QueryRow(
+		ctx,
+		`UPDATE LLMCache SET accessedAt = now() WHERE key = $1 AND expiresAt > now() RETURNING value`,
+		key,
+	).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, errors.InternalErr(err, "failed to read the cache")
+	}
+
+	return value, true, nil
+}
+
+// Set caches the value of the key. The expired values, & the least recently used ones beyond the
+// maximum entries, are deleted once every pruneEvery values set. The expiry is computed by
+// Postgres, like all the comparisons with it, so that it's not skewed by the clock of the app
+func (c *Postgres) Set(ctx context.Context, key string, value string) error {
+	_, err := c.pqdriver.Exec(
+		ctx,
+		`INSERT INTO LLMCache (key, value, expiresAt, accessedAt) VALUES ($1, $2, now() + make_interval(secs => $3), now())
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, expiresAt = EXCLUDED.expiresAt, accessedAt = now()`,
+		key,
+		value,
+		c.cfg.TTL.Seconds(),
+	)
+	if err != nil {
+		return errors.InternalErr(err, "failed to write the cache")
+	}
+
+	if c.sets.Add(1)%pruneEvery == 0 {
+		err = c.Prune(ctx)
+		if err != nil {
+			// the values are pruned again later, so the value set is not failed
+			logger.FromContext(ctx).Warnw("failed to prune the cache", "error", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Prune deletes the expired values, & the least recently used ones beyond the maximum entries
+func (c *Postgres) Prune(ctx context.Context) error {
+	_, err := c.pqdriver.Exec(ctx, `DELETE FROM LLMCache WHERE expiresAt <= now()`)
+	if err != nil {
+		return errors.InternalErr(err, "failed to delete the expired values of the cache")
+	}
+
+	_, err = c.pqdriver.Exec(
+		ctx,
+		`DELETE FROM LLMCache WHERE key IN (SELECT key FROM LLMCache ORDER BY accessedAt DESC OFFSET $1)`,
+		c.cfg.MaxEntries,
+	)
+	if err != nil {
+		return errors.InternalErr(err, "failed to evict the values of the cache")
+	}
+
+	return nil
+}
+
+// NewPostgres returns the cache stored in Postgres, the LLMCache table should be migrated
+func NewPostgres(pqdriver *pgxpool.Pool, cfg *Config) *Postgres {
+	return &Postgres{
+		cfg:      *cfg,
+		pqdriver: pqdriver,
+		sets:     &atomic.Int64{},
+	}
+}
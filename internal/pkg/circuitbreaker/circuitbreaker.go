@@ -0,0 +1,205 @@
+// Package circuitbreaker stops calling a failing dependency, so that the calls fail fast instead of
+// piling up on it, & lets it recover before calling it again.
+package circuitbreaker
+
+import (
+	stderrors "errors"
+	"sync"
+	"time"
+)
+
+// The states of a breaker
+const (
+	// StateClosed lets all the calls through, while their outcomes are recorded
+	StateClosed = "closed"
+	// StateOpen fails all the calls fast, until the open timeout elapses
+	StateOpen = "open"
+	// StateHalfOpen lets a single probe call through, which closes the breaker if it succeeds, or
+	// opens it again otherwise
+	StateHalfOpen = "half-open"
+)
+
+// Outcome is the outcome of a call let through by the breaker
+type Outcome int
+
+// The outcomes of the calls
+const (
+	// Success is the outcome of a call the dependency responded to
+	Success Outcome = iota
+	// Failure is the outcome of a call failed by the dependency
+	Failure
+	// Abandoned is the outcome of a call given up before the dependency responded, e.g. cancelled
+	// by its caller. It tells nothing of the dependency, so it doesn't change the state
+	Abandoned
+)
+
+// ErrOpen is returned by Allow when the breaker doesn't let the call through
+var ErrOpen = stderrors.New("circuit breaker is open")
+
+// Config is the configuration of a breaker
+type Config struct {
+	// Window is the number of the most recent calls, whose outcomes decide to open the breaker
+	Window int
+	// MinCalls is the minimum number of calls in the window, before the breaker can open
+	MinCalls int
+	// FailureRatio is the ratio of failed calls in the window, from 0 to 1, which opens the breaker.
+	// The breaker never opens when it's 0
+	FailureRatio float64
+	// OpenTimeout is how long the breaker stays open, before a probe call is let through
+	OpenTimeout time.Duration
+	// OnStateChange is called with the new state, whenever the state changes. It must not call the
+	// breaker
+	OnStateChange func(state string)
+}
+
+// Breaker is a circuit breaker, which opens when the ratio of failed calls crosses the threshold
+type Breaker struct {
+	cfg  Config
+	lock *sync.Mutex
+	now  func() time.Time
+
+	state string
+	// outcomes is a ring buffer of the outcomes of the recent calls, true if the call failed
+	outcomes []bool
+	next     int
+	calls    int
+	failures int
+	openedAt time.Time
+	probing  bool
+	// generation is incremented on every change of the state, so that the outcomes of the calls
+	// let through in a previous state are ignored
+	generation uint64
+}
+
+// State returns the current state of the breaker
+func (b *Breaker) State() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.halfOpenIfElapsed()
+	return b.state
+}
+
+// Allow returns ErrOpen if the call should fail fast. Otherwise, the call is let through & done
+// must be called with its outcome
+func (b *Breaker) Allow() (done func(outcome Outcome), err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.halfOpenIfElapsed()
+	probe := false
+	switch b.state {
+	case StateOpen:
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.probing {
+			return nil, ErrOpen
+		}
+		b.probing = true
+		probe = true
+	}
+
+	generation := b.generation
+	return func(outcome Outcome) {
+		b.record(generation, probe, outcome)
+	}, nil
+}
+
+// record records the outcome of a call let through in the generation, probe is true if the call
+// is the probe of the half-open breaker
+func (b *Breaker) record(generation uint64, probe bool, outcome Outcome) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	// the outcomes of the calls let through before the state changed are of no use, e.g. a call
+	// let through while closed, which ends once the breaker is half-open, is not the probe
+	if generation != b.generation {
+		return
+	}
+
+	if probe {
+		b.probing = false
+		switch outcome {
+		case Success:
+			b.reset()
+			b.setState(StateClosed)
+		case Failure:
+			b.open()
+		default:
+			// the next call is the probe instead
+		}
+		return
+	}
+
+	if outcome == Abandoned {
+		return
+	}
+
+	b.push(outcome == Failure)
+	if b.cfg.FailureRatio > 0 && b.calls >= b.cfg.MinCalls &&
+		float64(b.failures)/float64(b.calls) >= b.cfg.FailureRatio {
+		b.open()
+	}
+}
+
+// push adds the outcome to the window, replacing the oldest one once the window is full
+func (b *Breaker) push(failed bool) {
+	if b.calls == len(b.outcomes) {
+		if b.outcomes[b.next] {
+			b.failures--
+		}
+		b.calls--
+	}
+
+	b.outcomes[b.next] = failed
+	b.next = (b.next + 1) % len(b.outcomes)
+	b.calls++
+	if failed {
+		b.failures++
+	}
+}
+
+func (b *Breaker) reset() {
+	b.next = 0
+	b.calls = 0
+	b.failures = 0
+}
+
+func (b *Breaker) open() {
+	b.reset()
+	b.openedAt = b.now()
+	b.setState(StateOpen)
+}
+
+func (b *Breaker) halfOpenIfElapsed() {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.setState(StateHalfOpen)
+	}
+}
+
+func (b *Breaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+
+	b.state = state
+	b.generation++
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(state)
+	}
+}
+
+// New returns a closed breaker
+func New(cfg Config) *Breaker {
+	if cfg.Window < 1 {
+		cfg.Window = 1
+	}
+
+	return &Breaker{
+		cfg:      cfg,
+		lock:     &sync.Mutex{},
+		now:      time.Now,
+		state:    StateClosed,
+		outcomes: make([]bool, cfg.Window),
+	}
+}
@@ -0,0 +1,155 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+)
+
+func call(t *testing.T, b *Breaker, outcome Outcome) {
+	t.Helper()
+
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() error = %v, want the call let through", err)
+	}
+	done(outcome)
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	states := []string{}
+	b := New(Config{
+		Window:        4,
+		MinCalls:      4,
+		FailureRatio:  0.5,
+		OpenTimeout:   time.Minute,
+		OnStateChange: func(state string) { states = append(states, state) },
+	})
+	b.now = func() time.Time { return now }
+
+	// 1 failure in 4 calls is below the ratio
+	call(t, b, Success)
+	call(t, b, Failure)
+	call(t, b, Success)
+	call(t, b, Success)
+	if b.State() != StateClosed {
+		t.Fatalf("State() = %s, want %s", b.State(), StateClosed)
+	}
+
+	// the oldest success is replaced by a failure, 2 of the last 4 calls failed
+	call(t, b, Failure)
+	if b.State() != StateOpen {
+		t.Fatalf("State() = %s, want %s", b.State(), StateOpen)
+	}
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Errorf("Allow() error = %v, want %v while open", err, ErrOpen)
+	}
+
+	// a single probe is let through once the open timeout elapses
+	now = now.Add(time.Minute)
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() error = %v, want the probe let through", err)
+	}
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Errorf("Allow() error = %v, want %v while probing", err, ErrOpen)
+	}
+
+	// a failed probe opens the breaker again
+	done(Failure)
+	if b.State() != StateOpen {
+		t.Fatalf("State() = %s, want %s", b.State(), StateOpen)
+	}
+
+	now = now.Add(time.Minute)
+	call(t, b, Success)
+	if b.State() != StateClosed {
+		t.Fatalf("State() = %s, want %s", b.State(), StateClosed)
+	}
+
+	want := []string{StateOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateClosed}
+	if len(states) != len(want) {
+		t.Fatalf("state changes = %v, want %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("state changes = %v, want %v", states, want)
+			break
+		}
+	}
+}
+
+func TestBreaker_minCalls(t *testing.T) {
+	b := New(Config{Window: 10, MinCalls: 3, FailureRatio: 0.5, OpenTimeout: time.Minute})
+
+	call(t, b, Failure)
+	call(t, b, Failure)
+	if b.State() != StateClosed {
+		t.Fatalf("State() = %s, want %s before the minimum calls", b.State(), StateClosed)
+	}
+
+	call(t, b, Failure)
+	if b.State() != StateOpen {
+		t.Errorf("State() = %s, want %s", b.State(), StateOpen)
+	}
+}
+
+// newOpenBreaker returns a breaker opened by a failed call, whose open timeout elapses on advance
+func newOpenBreaker(t *testing.T) (b *Breaker, advance func()) {
+	t.Helper()
+
+	now := time.Now()
+	b = New(Config{Window: 1, MinCalls: 1, FailureRatio: 1, OpenTimeout: time.Minute})
+	b.now = func() time.Time { return now }
+	call(t, b, Failure)
+	if b.State() != StateOpen {
+		t.Fatalf("State() = %s, want %s", b.State(), StateOpen)
+	}
+
+	return b, func() { now = now.Add(time.Minute) }
+}
+
+func TestBreaker_abandonedProbe(t *testing.T) {
+	b, advance := newOpenBreaker(t)
+	advance()
+
+	// an abandoned probe doesn't close the breaker, the next call is the probe instead
+	call(t, b, Abandoned)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("State() = %s, want %s", b.State(), StateHalfOpen)
+	}
+
+	call(t, b, Success)
+	if b.State() != StateClosed {
+		t.Errorf("State() = %s, want %s", b.State(), StateClosed)
+	}
+}
+
+func TestBreaker_staleOutcome(t *testing.T) {
+	now := time.Now()
+	b := New(Config{Window: 2, MinCalls: 1, FailureRatio: 1, OpenTimeout: time.Minute})
+	b.now = func() time.Time { return now }
+
+	// a call let through while closed, which ends once the breaker is half-open
+	slow, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	call(t, b, Failure)
+	now = now.Add(time.Minute)
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() error = %v, want the probe let through", err)
+	}
+
+	// its outcome is not taken as the outcome of the probe
+	slow(Success)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("State() = %s, want %s", b.State(), StateHalfOpen)
+	}
+
+	probe(Failure)
+	if b.State() != StateOpen {
+		t.Errorf("State() = %s, want %s", b.State(), StateOpen)
+	}
+}
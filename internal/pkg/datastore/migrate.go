@@ -0,0 +1,344 @@
+package datastore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MigrationsTable is the table which keeps the history of applied migrations
+	MigrationsTable = "schema_migrations"
+	// migrationsLockID is the key of the Postgres advisory lock held while migrating, so that only
+	// one instance of the app migrates at a time
+	migrationsLockID int64 = 7361029384756102
+)
+
+// migrationFilePattern matches files named <version>_<name>.<up|down>.sql, e.g. 0001_create_users.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([\w-]+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned change of the schema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the SHA256 of the up migration, used to detect migrations edited after being applied
+	Checksum string
+}
+
+// MigrationStatus is the state of a migration in a database
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// appliedMigration is a row of the migrations history table
+type appliedMigration struct {
+	version   int64
+	checksum  string
+	appliedAt time.Time
+}
+
+// LoadMigrations reads all the migrations in the root of fsys, ordered by their version
+func LoadMigrations(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read migrations")
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid version of migration '%s'", entry.Name())
+		}
+
+		raw, err := fs.ReadFile(fsys, path.Clean(entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read migration '%s'", entry.Name())
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		}
+		if m.Name != matches[2] {
+			return nil, errors.Errorf("migrations '%s' & '%s' have the same version %d", m.Name, matches[2], version)
+		}
+
+		if matches[3] == "up" {
+			m.Up = string(raw)
+			checksum := sha256.Sum256(raw)
+			m.Checksum = hex.EncodeToString(checksum[:])
+		} else {
+			m.Down = string(raw)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, errors.Errorf("up migration of version %d (%s) is missing", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// pendingMigrations returns the migrations which are yet to be applied. An error is returned if any
+// of the applied migrations was edited after being applied, or is missing
+func pendingMigrations(migrations []*Migration, applied map[int64]appliedMigration) ([]*Migration, error) {
+	known := make(map[int64]bool, len(migrations))
+	pending := make([]*Migration, 0)
+	for _, m := range migrations {
+		known[m.Version] = true
+
+		am, ok := applied[m.Version]
+		if !ok {
+			pending = append(pending, m)
+			continue
+		}
+
+		if am.checksum != m.Checksum {
+			return nil, errors.Errorf(
+				"migration %d (%s) was modified after it was applied, checksum %s != %s",
+				m.Version, m.Name, m.Checksum, am.checksum,
+			)
+		}
+	}
+
+	for version := range applied {
+		if !known[version] {
+			return nil, errors.Errorf("migration %d is applied, but is missing", version)
+		}
+	}
+
+	return pending, nil
+}
+
+// Migrator applies & rolls back migrations on a Postgres database
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []*Migration
+}
+
+// Up applies all the pending migrations in order, each within its own transaction
+func (mg *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	applied := make([]*Migration, 0)
+	err := mg.withLock(ctx, func(conn *pgxpool.Conn) error {
+		history, err := mg.history(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		pending, err := pendingMigrations(mg.migrations, history)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range pending {
+			err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, m.Up)
+				if err != nil {
+					return errors.Wrapf(err, "failed to apply migration %d (%s)", m.Version, m.Name)
+				}
+
+				_, err = tx.Exec(
+					ctx,
+					fmt.Sprintf("INSERT INTO %s (version, name, checksum) VALUES ($1, $2, $3)", MigrationsTable),
+					m.Version, m.Name, m.Checksum,
+				)
+				if err != nil {
+					return errors.Wrapf(err, "failed to record migration %d (%s)", m.Version, m.Name)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			applied = append(applied, m)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down rolls back the given number of most recently applied migrations, in reverse order
+func (mg *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	rolledback := make([]*Migration, 0, steps)
+	err := mg.withLock(ctx, func(conn *pgxpool.Conn) error {
+		history, err := mg.history(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		_, err = pendingMigrations(mg.migrations, history)
+		if err != nil {
+			return err
+		}
+
+		for i := len(mg.migrations) - 1; i >= 0 && len(rolledback) < steps; i-- {
+			m := mg.migrations[i]
+			if _, ok := history[m.Version]; !ok {
+				continue
+			}
+
+			if m.Down == "" {
+				return errors.Errorf("down migration of version %d (%s) is missing", m.Version, m.Name)
+			}
+
+			err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, m.Down)
+				if err != nil {
+					return errors.Wrapf(err, "failed to roll back migration %d (%s)", m.Version, m.Name)
+				}
+
+				_, err = tx.Exec(
+					ctx,
+					fmt.Sprintf("DELETE FROM %s WHERE version = $1", MigrationsTable),
+					m.Version,
+				)
+				if err != nil {
+					return errors.Wrapf(err, "failed to remove migration %d (%s) from history", m.Version, m.Name)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			rolledback = append(rolledback, m)
+		}
+
+		return nil
+	})
+
+	return rolledback, err
+}
+
+// Status returns all the known migrations, along with the time they were applied
+func (mg *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	list := make([]MigrationStatus, 0, len(mg.migrations))
+	err := mg.withLock(ctx, func(conn *pgxpool.Conn) error {
+		history, err := mg.history(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range mg.migrations {
+			ms := MigrationStatus{Version: m.Version, Name: m.Name}
+			if am, ok := history[m.Version]; ok {
+				appliedAt := am.appliedAt
+				ms.AppliedAt = &appliedAt
+			}
+			list = append(list, ms)
+		}
+
+		return nil
+	})
+
+	return list, err
+}
+
+// withLock runs fn holding the migrations advisory lock, on a single connection
+func (mg *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := mg.pool.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to acquire connection")
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationsLockID)
+	if err != nil {
+		return errors.Wrap(err, "failed to acquire migrations lock")
+	}
+	defer func() {
+		// the lock has to be released even if ctx is done
+		_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationsLockID)
+	}()
+
+	_, err = conn.Exec(ctx, fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			appliedAt timestamptz NOT NULL DEFAULT now()
+		)`,
+		MigrationsTable,
+	))
+	if err != nil {
+		return errors.Wrap(err, "failed to create migrations table")
+	}
+
+	return fn(conn)
+}
+
+// history returns all the applied migrations
+func (mg *Migrator) history(ctx context.Context, conn *pgxpool.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.Query(ctx, fmt.Sprintf("SELECT version, checksum, appliedAt FROM %s", MigrationsTable))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read migrations history")
+	}
+	defer rows.Close()
+
+	history := map[int64]appliedMigration{}
+	for rows.Next() {
+		am := appliedMigration{}
+		err = rows.Scan(&am.version, &am.checksum, &am.appliedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read migrations history")
+		}
+		history[am.version] = am
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read migrations history")
+	}
+
+	return history, nil
+}
+
+// NewMigrator returns a Migrator for the migrations in the root of fsys
+func NewMigrator(pool *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		pool:       pool,
+		migrations: migrations,
+	}, nil
+}
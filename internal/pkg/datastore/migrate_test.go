@@ -0,0 +1,131 @@
+package datastore
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name         string
+		fsys         fstest.MapFS
+		wantVersions []int64
+		wantErr      bool
+	}{
+		{
+			name: "ordered by version",
+			fsys: fstest.MapFS{
+				"0002_add_mobile.up.sql":     {Data: []byte("ALTER TABLE Users ADD mobile TEXT;")},
+				"0002_add_mobile.down.sql":   {Data: []byte("ALTER TABLE Users DROP mobile;")},
+				"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE Users (id BIGSERIAL);")},
+				"0001_create_users.down.sql": {Data: []byte("DROP TABLE Users;")},
+				"0010_add_index.up.sql":      {Data: []byte("CREATE INDEX ON Users (id);")},
+				"README.md":                  {Data: []byte("not a migration")},
+			},
+			wantVersions: []int64{1, 2, 10},
+		},
+		{
+			name: "missing up migration",
+			fsys: fstest.MapFS{
+				"0001_create_users.down.sql": {Data: []byte("DROP TABLE Users;")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"0001_create_users.up.sql": {Data: []byte("CREATE TABLE Users (id BIGSERIAL);")},
+				"0001_create_notes.up.sql": {Data: []byte("CREATE TABLE Notes (id BIGSERIAL);")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := LoadMigrations(tt.fsys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadMigrations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(migrations) != len(tt.wantVersions) {
+				t.Fatalf("got %d migrations, want %d", len(migrations), len(tt.wantVersions))
+			}
+			for i, m := range migrations {
+				if m.Version != tt.wantVersions[i] {
+					t.Errorf("migration[%d].Version = %d, want %d", i, m.Version, tt.wantVersions[i])
+				}
+				if m.Checksum == "" {
+					t.Errorf("migration[%d] has no checksum", i)
+				}
+			}
+		})
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(fstest.MapFS{
+		"0001_create_users.up.sql": {Data: []byte("CREATE TABLE Users (id BIGSERIAL);")},
+		"0002_add_mobile.up.sql":   {Data: []byte("ALTER TABLE Users ADD mobile TEXT;")},
+	})
+	if err != nil {
+		t.Fatalf("LoadMigrations() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		applied      map[int64]appliedMigration
+		wantVersions []int64
+		wantErr      bool
+	}{
+		{
+			name:         "nothing applied",
+			applied:      map[int64]appliedMigration{},
+			wantVersions: []int64{1, 2},
+		},
+		{
+			name: "partially applied",
+			applied: map[int64]appliedMigration{
+				1: {version: 1, checksum: migrations[0].Checksum},
+			},
+			wantVersions: []int64{2},
+		},
+		{
+			name: "edited after being applied",
+			applied: map[int64]appliedMigration{
+				1: {version: 1, checksum: "edited"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "applied migration is missing",
+			applied: map[int64]appliedMigration{
+				1: {version: 1, checksum: migrations[0].Checksum},
+				3: {version: 3, checksum: "removed"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pending, err := pendingMigrations(migrations, tt.applied)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pendingMigrations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(pending) != len(tt.wantVersions) {
+				t.Fatalf("got %d pending migrations, want %d", len(pending), len(tt.wantVersions))
+			}
+			for i, m := range pending {
+				if m.Version != tt.wantVersions[i] {
+					t.Errorf("pending[%d].Version = %d, want %d", i, m.Version, tt.wantVersions[i])
+				}
+			}
+		})
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"time"
 
+	"github.com/mohamedveron/go_app_template/internal/pkg/health"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
 )
 
@@ -26,6 +29,10 @@ type MongoConfig struct {
 	MaxPoolSize            uint64
 	MinPoolSize            uint64
 	ServerSelectionTimeout time.Duration
+	// PoolMonitor is notified of the connection pool events, if set
+	PoolMonitor *event.PoolMonitor
+	// CommandMonitor is notified of the commands, if set
+	CommandMonitor *event.CommandMonitor
 }
 
 // MongoDB - mongodb connection service. A single instance of this struct can be
@@ -64,6 +71,11 @@ func (mdb *MongoDB) Disconnect(ctx context.Context) error {
 	return nil
 }
 
+// Ping checks if the primary is reachable
+func (mdb *MongoDB) Ping(ctx context.Context) error {
+	return mdb.client.Ping(ctx, readpref.Primary())
+}
+
 // Collection returns mongodb collection by given name
 func (mdb *MongoDB) Collection(name string) *mongo.Collection {
 	return mdb.Database.Collection(name)
@@ -165,12 +177,19 @@ func mongoOpts(cfg *MongoConfig) (*options.ClientOptions, string, error) {
 	opts.SetMinPoolSize(cfg.MinPoolSize)
 	opts.SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
 	opts.SetConnectTimeout(cfg.ConnectTimeout)
+	if cfg.PoolMonitor != nil {
+		opts.SetPoolMonitor(cfg.PoolMonitor)
+	}
+	if cfg.CommandMonitor != nil {
+		opts.SetMonitor(cfg.CommandMonitor)
+	}
 
 	setDefaults(opts)
 
 	return opts, cs.Database, nil
 }
 
+// NewMongoService connects to MongoDB, & registers its health check
 func NewMongoService(ctx context.Context, cfg *MongoConfig) (*MongoDB, error) {
 	mdb := &MongoDB{
 		config: cfg,
@@ -200,5 +219,11 @@ func NewMongoService(ctx context.Context, cfg *MongoConfig) (*MongoDB, error) {
 		return nil, errors.Wrap(err, "failed to ping MongoDB")
 	}
 
+	health.Register(health.Check{
+		Name:     "mongodb",
+		Critical: true,
+		Check:    mdb.Ping,
+	})
+
 	return mdb, nil
 }
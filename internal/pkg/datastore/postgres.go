@@ -8,7 +8,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mohamedveron/go_app_template/internal/pkg/health"
 )
 
 // Config struct holds all the configurations required the datastore package
@@ -28,6 +30,11 @@ type Config struct {
 	WriteTimeout time.Duration `json:"writeTimeout,omitempty"`
 	IdleTimeout  time.Duration `json:"idleTimeout,omitempty"`
 	DialTimeout  time.Duration `json:"dialTimeout,omitempty"`
+
+	// AutoMigrate applies all pending migrations when the app starts
+	AutoMigrate bool `json:"autoMigrate,omitempty"`
+	// Tracer traces the queries, if set
+	Tracer pgx.QueryTracer `json:"-"`
 }
 
 // ConnURL returns the connection URL
@@ -49,7 +56,7 @@ func (cfg *Config) ConnURL() string {
 	)
 }
 
-// NewService returns a new instance of PGX pool
+// NewPostgresService returns a new instance of PGX pool, & registers its health check
 func NewPostgresService(cfg *Config) (*pgxpool.Pool, error) {
 	poolcfg, err := pgxpool.ParseConfig(cfg.ConnURL())
 	if err != nil {
@@ -62,11 +69,18 @@ func NewPostgresService(cfg *Config) (*pgxpool.Pool, error) {
 	dialer := &net.Dialer{KeepAlive: cfg.DialTimeout}
 	dialer.Timeout = cfg.DialTimeout
 	poolcfg.ConnConfig.DialFunc = dialer.DialContext
+	poolcfg.ConnConfig.Tracer = cfg.Tracer
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolcfg)
 	if err != nil {
 		return nil, errors.New("failed to create pgx pool")
 	}
 
+	health.Register(health.Check{
+		Name:     "postgres",
+		Critical: true,
+		Check:    pool.Ping,
+	})
+
 	return pool, nil
 }
@@ -0,0 +1,271 @@
+// Package errors provides typed errors used across the application. Every error carries a kind,
+// a message which is safe to be shown to the consumers of the APIs, an optional wrapped cause &
+// the stack trace of where it was created.
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// Kind is the category of an error, which decides how it is presented to the consumers
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindPreconditionFailed
+	KindPreconditionRequired
+	KindRateLimited
+	KindBadGateway
+	KindGatewayTimeout
+	KindUnavailable
+)
+
+const (
+	defaultInternalMessage = "unknown error occurred"
+	maxStackDepth          = 32
+)
+
+// Error is the typed error of the application
+type Error struct {
+	kind    Kind
+	message string
+	cause   error
+	stack   []uintptr
+}
+
+// Error returns the message along with the message of the wrapped cause
+func (e *Error) Error() string {
+	if e.cause == nil {
+		return e.message
+	}
+
+	return fmt.Sprintf("%s: %s", e.message, e.cause.Error())
+}
+
+// Unwrap returns the wrapped cause
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Kind returns the kind of the error
+func (e *Error) Kind() Kind {
+	return e.kind
+}
+
+// Message returns the public message of the error
+func (e *Error) Message() string {
+	return e.message
+}
+
+// Format implements fmt.Formatter. '%+v' prints the error along with its stack trace
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		_, _ = io.WriteString(s, e.Error())
+		if s.Flag('+') {
+			_, _ = io.WriteString(s, e.stacktrace())
+		}
+	case 's':
+		_, _ = io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+func (e *Error) stacktrace() string {
+	sb := strings.Builder{}
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&sb, "\n%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+
+	return sb.String()
+}
+
+func newErr(kind Kind, cause error, message string) *Error {
+	pcs := make([]uintptr, maxStackDepth)
+	// skip runtime.Callers, newErr & the exported constructor
+	n := runtime.Callers(3, pcs)
+
+	return &Error{
+		kind:    kind,
+		message: message,
+		cause:   cause,
+		stack:   pcs[:n],
+	}
+}
+
+// New returns a new internal error with the given message
+func New(message string) error {
+	return newErr(KindInternal, nil, message)
+}
+
+// Internal returns a new internal error
+func Internal(message string) error {
+	return newErr(KindInternal, nil, message)
+}
+
+// InternalErr wraps the given error as an internal error
+func InternalErr(err error, message string) error {
+	return newErr(KindInternal, err, message)
+}
+
+// NotFound returns a new error for a resource which does not exist
+func NotFound(message string) error {
+	return newErr(KindNotFound, nil, message)
+}
+
+// NotFoundErr wraps the given error as a not found error
+func NotFoundErr(err error, message string) error {
+	return newErr(KindNotFound, err, message)
+}
+
+// Conflict returns a new error for a resource which conflicts with an existing one
+func Conflict(message string) error {
+	return newErr(KindConflict, nil, message)
+}
+
+// ConflictErr wraps the given error as a conflict error
+func ConflictErr(err error, message string) error {
+	return newErr(KindConflict, err, message)
+}
+
+// Validation returns a new error for invalid input
+func Validation(message string) error {
+	return newErr(KindValidation, nil, message)
+}
+
+// ValidationErr wraps the given error as a validation error
+func ValidationErr(err error, message string) error {
+	return newErr(KindValidation, err, message)
+}
+
+// Unauthorized returns a new error for unauthenticated requests
+func Unauthorized(message string) error {
+	return newErr(KindUnauthorized, nil, message)
+}
+
+// UnauthorizedErr wraps the given error as an unauthorized error
+func UnauthorizedErr(err error, message string) error {
+	return newErr(KindUnauthorized, err, message)
+}
+
+// Forbidden returns a new error for requests which are not permitted
+func Forbidden(message string) error {
+	return newErr(KindForbidden, nil, message)
+}
+
+// ForbiddenErr wraps the given error as a forbidden error
+func ForbiddenErr(err error, message string) error {
+	return newErr(KindForbidden, err, message)
+}
+
+// PreconditionFailed returns a new error for requests whose precondition, e.g. If-Match, is not
+// met by the current state of the resource
+func PreconditionFailed(message string) error {
+	return newErr(KindPreconditionFailed, nil, message)
+}
+
+// PreconditionRequired returns a new error for requests which are required to be conditional
+func PreconditionRequired(message string) error {
+	return newErr(KindPreconditionRequired, nil, message)
+}
+
+// RateLimitedErr wraps the given error as an error for requests which are rate limited, by the app
+// or by the upstream service it depends on
+func RateLimitedErr(err error, message string) error {
+	return newErr(KindRateLimited, err, message)
+}
+
+// BadGatewayErr wraps the given error as an error for a failed or invalid response of an upstream
+// service
+func BadGatewayErr(err error, message string) error {
+	return newErr(KindBadGateway, err, message)
+}
+
+// GatewayTimeoutErr wraps the given error as an error for an upstream service which did not respond
+// in time
+func GatewayTimeoutErr(err error, message string) error {
+	return newErr(KindGatewayTimeout, err, message)
+}
+
+// UnavailableErr wraps the given error as an error for a service which is temporarily unavailable,
+// e.g. an upstream service which is failing fast
+func UnavailableErr(err error, message string) error {
+	return newErr(KindUnavailable, err, message)
+}
+
+// KindOf returns the kind of the first typed error in the chain of err. Any error which is not
+// typed is considered internal
+func KindOf(err error) Kind {
+	e := new(Error)
+	if errors.As(err, &e) {
+		return e.kind
+	}
+
+	return KindInternal
+}
+
+// Is reports whether any error in err's chain matches target
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+// As finds the first error in err's chain that matches target
+func As(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
+
+// HTTPStatusCodeMessage returns the HTTP status code & the public message for the given error.
+// Internal errors never expose their message. isErr is false only when err is nil
+func HTTPStatusCodeMessage(err error) (status int, message string, isErr bool) {
+	if err == nil {
+		return http.StatusOK, "", false
+	}
+
+	e := new(Error)
+	if !errors.As(err, &e) {
+		return http.StatusInternalServerError, defaultInternalMessage, true
+	}
+
+	switch e.kind {
+	case KindNotFound:
+		return http.StatusNotFound, e.message, true
+	case KindConflict:
+		return http.StatusConflict, e.message, true
+	case KindValidation:
+		return http.StatusBadRequest, e.message, true
+	case KindUnauthorized:
+		return http.StatusUnauthorized, e.message, true
+	case KindForbidden:
+		return http.StatusForbidden, e.message, true
+	case KindPreconditionFailed:
+		return http.StatusPreconditionFailed, e.message, true
+	case KindPreconditionRequired:
+		return http.StatusPreconditionRequired, e.message, true
+	case KindRateLimited:
+		return http.StatusTooManyRequests, e.message, true
+	case KindBadGateway:
+		return http.StatusBadGateway, e.message, true
+	case KindGatewayTimeout:
+		return http.StatusGatewayTimeout, e.message, true
+	case KindUnavailable:
+		return http.StatusServiceUnavailable, e.message, true
+	default:
+		return http.StatusInternalServerError, defaultInternalMessage, true
+	}
+}
@@ -0,0 +1,160 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHTTPStatusCodeMessage(t *testing.T) {
+	cause := errors.New("connection refused")
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantMessage string
+		wantIsErr   bool
+	}{
+		{
+			name:       "nil error",
+			err:        nil,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "untyped error",
+			err:         cause,
+			wantStatus:  http.StatusInternalServerError,
+			wantMessage: defaultInternalMessage,
+			wantIsErr:   true,
+		},
+		{
+			name:        "internal error hides its message",
+			err:         InternalErr(cause, "failed to query"),
+			wantStatus:  http.StatusInternalServerError,
+			wantMessage: defaultInternalMessage,
+			wantIsErr:   true,
+		},
+		{
+			name:        "not found",
+			err:         NotFound("user not found"),
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "user not found",
+			wantIsErr:   true,
+		},
+		{
+			name:        "conflict",
+			err:         ConflictErr(cause, "user already exists"),
+			wantStatus:  http.StatusConflict,
+			wantMessage: "user already exists",
+			wantIsErr:   true,
+		},
+		{
+			name:        "validation",
+			err:         Validation("invalid email address provided"),
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "invalid email address provided",
+			wantIsErr:   true,
+		},
+		{
+			name:        "unauthorized",
+			err:         Unauthorized("missing token"),
+			wantStatus:  http.StatusUnauthorized,
+			wantMessage: "missing token",
+			wantIsErr:   true,
+		},
+		{
+			name:        "forbidden",
+			err:         Forbidden("not allowed"),
+			wantStatus:  http.StatusForbidden,
+			wantMessage: "not allowed",
+			wantIsErr:   true,
+		},
+		{
+			name:        "precondition failed",
+			err:         PreconditionFailed("version mismatch"),
+			wantStatus:  http.StatusPreconditionFailed,
+			wantMessage: "version mismatch",
+			wantIsErr:   true,
+		},
+		{
+			name:        "precondition required",
+			err:         PreconditionRequired("If-Match is required"),
+			wantStatus:  http.StatusPreconditionRequired,
+			wantMessage: "If-Match is required",
+			wantIsErr:   true,
+		},
+		{
+			name:        "rate limited",
+			err:         RateLimitedErr(cause, "too many requests"),
+			wantStatus:  http.StatusTooManyRequests,
+			wantMessage: "too many requests",
+			wantIsErr:   true,
+		},
+		{
+			name:        "bad gateway",
+			err:         BadGatewayErr(cause, "upstream failed"),
+			wantStatus:  http.StatusBadGateway,
+			wantMessage: "upstream failed",
+			wantIsErr:   true,
+		},
+		{
+			name:        "gateway timeout",
+			err:         GatewayTimeoutErr(cause, "upstream timed out"),
+			wantStatus:  http.StatusGatewayTimeout,
+			wantMessage: "upstream timed out",
+			wantIsErr:   true,
+		},
+		{
+			name:        "unavailable",
+			err:         UnavailableErr(cause, "upstream is failing"),
+			wantStatus:  http.StatusServiceUnavailable,
+			wantMessage: "upstream is failing",
+			wantIsErr:   true,
+		},
+		{
+			name:        "typed error wrapped with fmt",
+			err:         fmt.Errorf("read user: %w", NotFound("user not found")),
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "user not found",
+			wantIsErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, message, isErr := HTTPStatusCodeMessage(tt.err)
+			if status != tt.wantStatus || message != tt.wantMessage || isErr != tt.wantIsErr {
+				t.Errorf(
+					"HTTPStatusCodeMessage() = (%d, %q, %v), want (%d, %q, %v)",
+					status, message, isErr,
+					tt.wantStatus, tt.wantMessage, tt.wantIsErr,
+				)
+			}
+		})
+	}
+}
+
+func TestError_Unwrap(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := InternalErr(cause, "failed to query")
+	if !Is(err, cause) {
+		t.Fatalf("expected %v to wrap %v", err, cause)
+	}
+
+	if KindOf(err) != KindInternal {
+		t.Errorf("KindOf() = %v, want %v", KindOf(err), KindInternal)
+	}
+}
+
+func TestError_Format(t *testing.T) {
+	err := NotFound("user not found")
+	if got := fmt.Sprintf("%v", err); got != "user not found" {
+		t.Errorf("%%v = %q, want %q", got, "user not found")
+	}
+
+	withStack := fmt.Sprintf("%+v", err)
+	if !strings.Contains(withStack, "TestError_Format") {
+		t.Errorf("expected stack trace to contain the caller, got %q", withStack)
+	}
+}
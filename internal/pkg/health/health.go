@@ -0,0 +1,154 @@
+// Package health runs the checks of the dependencies of the app, to report whether the app is
+// ready to serve. Components register their own checks, e.g. when they're connected.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
+)
+
+const (
+	// StatusUp is the status of a check which passed, & of a report whose checks all passed
+	StatusUp = "up"
+	// StatusDown is the status of a check which failed, & of a report with a failed critical check
+	StatusDown = "down"
+	// StatusDegraded is the status of a report, whose failed checks are all non-critical
+	StatusDegraded = "degraded"
+)
+
+// Check is the check of a dependency
+type Check struct {
+	// Name identifies the dependency, e.g. postgres. A check replaces the one with the same name
+	Name string
+	// Critical checks make the app not ready when they fail
+	Critical bool
+	// Check returns an error if the dependency is not available. It should return when ctx is done
+	Check func(ctx context.Context) error
+}
+
+// Result is the result of a check. The error of a failed check is logged rather than reported,
+// since it may reveal the hosts & the configuration of the dependency to the callers
+type Result struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	// Latency is how long the check took, in milliseconds
+	Latency float64 `json:"latencyMs"`
+}
+
+// Report has the results of all the checks, by the name of the check
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Ready reports whether all the critical checks passed
+func (r *Report) Ready() bool {
+	return r.Status != StatusDown
+}
+
+// Registry has the checks of all the dependencies
+type Registry struct {
+	lock   *sync.RWMutex
+	checks map[string]Check
+}
+
+// Register adds the check to the registry, replacing any check with the same name
+func (reg *Registry) Register(c Check) {
+	reg.lock.Lock()
+	reg.checks[c.Name] = c
+	reg.lock.Unlock()
+}
+
+// Run runs all the checks in parallel, each with the given timeout
+func (reg *Registry) Run(ctx context.Context, timeout time.Duration) *Report {
+	reg.lock.RLock()
+	checks := make([]Check, 0, len(reg.checks))
+	for _, c := range reg.checks {
+		checks = append(checks, c)
+	}
+	reg.lock.RUnlock()
+
+	report := &Report{
+		Status: StatusUp,
+		Checks: make(map[string]Result, len(checks)),
+	}
+
+	results := make([]Result, len(checks))
+	wg := &sync.WaitGroup{}
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = run(ctx, checks[i], timeout)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		result := results[i]
+		report.Checks[c.Name] = result
+		if result.Status == StatusUp {
+			continue
+		}
+
+		if c.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+// run runs the check with the timeout. A check which doesn't return in time is failed, without
+// waiting for it
+func run(ctx context.Context, c Check, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errors.InternalErr(ctx.Err(), "check timed out")
+	}
+
+	result := Result{
+		Status:   StatusUp,
+		Critical: c.Critical,
+		Latency:  float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		logger.FromContext(ctx).Warnw("health check failed", "check", c.Name, "critical", c.Critical, "error", err.Error())
+	}
+
+	return result
+}
+
+// NewRegistry returns a registry without any checks
+func NewRegistry() *Registry {
+	return &Registry{
+		lock:   &sync.RWMutex{},
+		checks: map[string]Check{},
+	}
+}
+
+// Default is the registry with the checks of the app, components register their checks here
+var Default = NewRegistry()
+
+// Register adds the check to the default registry
+func Register(c Check) {
+	Default.Register(c)
+}
@@ -0,0 +1,132 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+)
+
+func up(context.Context) error {
+	return nil
+}
+
+func down(context.Context) error {
+	return errors.Internal("connection refused")
+}
+
+// hang ignores the context, as a misbehaving check would
+func hang(context.Context) error {
+	time.Sleep(time.Second)
+	return nil
+}
+
+func TestRegistry_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantStatus string
+		wantChecks map[string]string
+	}{
+		{
+			name:       "no checks",
+			wantStatus: StatusUp,
+			wantChecks: map[string]string{},
+		},
+		{
+			name: "all up",
+			checks: []Check{
+				{Name: "postgres", Critical: true, Check: up},
+				{Name: "openai", Check: up},
+			},
+			wantStatus: StatusUp,
+			wantChecks: map[string]string{"postgres": StatusUp, "openai": StatusUp},
+		},
+		{
+			name: "critical down",
+			checks: []Check{
+				{Name: "postgres", Critical: true, Check: down},
+				{Name: "openai", Check: up},
+			},
+			wantStatus: StatusDown,
+			wantChecks: map[string]string{"postgres": StatusDown, "openai": StatusUp},
+		},
+		{
+			name: "non-critical down",
+			checks: []Check{
+				{Name: "postgres", Critical: true, Check: up},
+				{Name: "openai", Check: down},
+			},
+			wantStatus: StatusDegraded,
+			wantChecks: map[string]string{"postgres": StatusUp, "openai": StatusDown},
+		},
+		{
+			name: "timed out",
+			checks: []Check{
+				{Name: "mongodb", Critical: true, Check: hang},
+			},
+			wantStatus: StatusDown,
+			wantChecks: map[string]string{"mongodb": StatusDown},
+		},
+		{
+			name: "replaced",
+			checks: []Check{
+				{Name: "postgres", Critical: true, Check: down},
+				{Name: "postgres", Critical: true, Check: up},
+			},
+			wantStatus: StatusUp,
+			wantChecks: map[string]string{"postgres": StatusUp},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := NewRegistry()
+			for _, c := range tt.checks {
+				reg.Register(c)
+			}
+
+			start := time.Now()
+			report := reg.Run(context.Background(), 50*time.Millisecond)
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("Run() took %s, should not wait for the checks beyond the timeout", elapsed)
+			}
+
+			if report.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", report.Status, tt.wantStatus)
+			}
+			if report.Ready() != (tt.wantStatus != StatusDown) {
+				t.Errorf("Ready() = %v for status %s", report.Ready(), report.Status)
+			}
+			if len(report.Checks) != len(tt.wantChecks) {
+				t.Fatalf("got %d checks, want %d", len(report.Checks), len(tt.wantChecks))
+			}
+			for name, want := range tt.wantChecks {
+				got := report.Checks[name]
+				if got.Status != want {
+					t.Errorf("check %s status = %s, want %s", name, got.Status, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_Run_parallel(t *testing.T) {
+	reg := NewRegistry()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		reg.Register(Check{Name: name, Check: func(context.Context) error {
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		}})
+	}
+
+	start := time.Now()
+	report := reg.Run(context.Background(), time.Second)
+	// the checks would take 400ms if run one after the other
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("Run() took %s, the checks should run in parallel", elapsed)
+	}
+	if report.Status != StatusUp {
+		t.Errorf("Status = %s, want %s", report.Status, StatusUp)
+	}
+}
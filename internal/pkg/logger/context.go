@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type ctxKey struct{}
+
+// WithContext returns a copy of ctx with a logger, which adds the given key-value pairs to every
+// line it logs. The pairs are added to the logger already in ctx if any, otherwise to the global
+// logger. Use it to add request scoped fields, e.g. request ID, once, to all the logs downstream
+func WithContext(ctx context.Context, args ...interface{}) context.Context {
+	return context.WithValue(ctx, ctxKey{}, FromContext(ctx).With(args...))
+}
+
+// FromContext returns the logger in ctx, or the global logger if there's none
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(ctxKey{}).(Logger); ok {
+		return l
+	}
+
+	// the global logger skips a caller for the package level wrappers, which is not required when
+	// it's used directly
+	return log.WithOptions(zap.AddCallerSkip(-1))
+}
+
+// Replace replaces the global logger with l, & returns a function which restores the previous one.
+// It's meant for tests, to capture the logs with an observer core. The fields are redacted as they
+// are by the global logger
+func Replace(l *zap.Logger) (restore func()) {
+	prev := log
+	log = l.WithOptions(
+		zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			return newRedactCore(c, redactKeys)
+		}),
+		zap.AddCallerSkip(1),
+	).Sugar()
+
+	return func() {
+		log = prev
+	}
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWithContext(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	defer Replace(zap.New(core))()
+
+	ctx := WithContext(context.Background(), "requestID", "abc")
+	ctx = WithContext(ctx, "userID", int64(42))
+	FromContext(ctx).Infow("user read", "cached", false)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+
+	got := entries[0].ContextMap()
+	want := map[string]interface{}{
+		"requestID": "abc",
+		"userID":    int64(42),
+		"cached":    false,
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("field %s = %v, want %v", key, got[key], value)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	defer Replace(zap.New(core, zap.AddCaller()))()
+
+	FromContext(context.Background()).Info("no logger in context")
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	if len(entries[0].Context) != 0 {
+		t.Errorf("got fields %v, want none", entries[0].ContextMap())
+	}
+	if file := entries[0].Caller.File; !entries[0].Caller.Defined || !strings.HasSuffix(file, "context_test.go") {
+		t.Errorf("caller = %s, want context_test.go", file)
+	}
+}
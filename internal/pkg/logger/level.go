@@ -0,0 +1,34 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ParseLevel returns the level of its name, i.e. debug, info, warn or error
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return DebugLevel, nil
+	case "info", "":
+		return InfoLevel, nil
+	case "warn":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	default:
+		return InfoLevel, fmt.Errorf("unknown log level '%s'", name)
+	}
+}
+
+// SetLevel changes the level of the global logger, & of all the loggers derived from it
+func SetLevel(l Level) {
+	level.SetLevel(getLevel(l))
+}
+
+// LevelHandler returns an HTTP handler which responds with the current level of the global logger
+// on GET, & changes it on PUT. Both the request & the response are JSON, e.g. {"level":"debug"}
+func LevelHandler() http.Handler {
+	return level
+}
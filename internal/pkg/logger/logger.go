@@ -18,24 +18,41 @@ const (
 	ErrorLevel
 )
 
+var (
+	// level is the level of the global logger, which can be changed at runtime
+	level = zap.NewAtomicLevel()
+	// redactKeys are the keys of the fields redacted by the global logger
+	redactKeys = DefaultRedactKeys
+)
+
 func init() {
-	core := zap.NewProductionConfig()
-	core.EncoderConfig.TimeKey = "timestamp"
-	core.EncoderConfig.MessageKey = "message"
-	core.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	customLog, _ := core.Build()
-	log = customLog.WithOptions(zap.AddCallerSkip(1)).Sugar()
+	log = build()
+}
+
+// Config is the configuration of the global logger
+type Config struct {
+	Level Level
+	// RedactKeys are the keys of the fields whose values are masked, they're case insensitive
+	RedactKeys []string
 }
 
-// Config sets configurations for global logger
-func Config(level Level) {
+// Configure sets configurations for global logger
+func Configure(cfg *Config) {
+	SetLevel(cfg.Level)
+	redactKeys = cfg.RedactKeys
+	log = build()
+}
+
+func build() Logger {
 	core := zap.NewProductionConfig()
-	core.Level = zap.NewAtomicLevelAt(getLevel(level))
-	core.EncoderConfig.MessageKey = "message"
+	core.Level = level
 	core.EncoderConfig.TimeKey = "timestamp"
+	core.EncoderConfig.MessageKey = "message"
 	core.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	customLog, _ := core.Build()
-	log = customLog.WithOptions(zap.AddCallerSkip(1)).Sugar()
+	customLog, _ := core.Build(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return newRedactCore(c, redactKeys)
+	}))
+	return customLog.WithOptions(zap.AddCallerSkip(1)).Sugar()
 }
 
 // With adds a variadic number of fields to the logging context. It accepts a
@@ -197,6 +214,17 @@ func Fatalw(msg string, keysAndValues ...interface{}) {
 	log.Fatalw(msg, keysAndValues...)
 }
 
+// ErrWithStacktrace logs the error along with its stack trace, if the error has one. The error is
+// logged as the error field & not as the message, so that its text is redacted. The stack trace is
+// read by formatting the error with '%+v', as errorVerbose
+func ErrWithStacktrace(err error) {
+	if err == nil {
+		return
+	}
+
+	log.Errorw("unexpected error", "error", err)
+}
+
 func getLevel(level Level) zapcore.Level {
 	switch level {
 	case InfoLevel:
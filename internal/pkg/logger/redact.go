@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces the values of the redacted fields
+const Redacted = "[REDACTED]"
+
+// DefaultRedactKeys are the keys of the fields redacted, unless configured otherwise
+var DefaultRedactKeys = []string{"password", "email", "mobile", "authorization"}
+
+// errorKey is the key of the logged errors, whose text is redacted by its content rather than masked
+// by its key, since it's free text. e.g. the error of a datastore may have the values of a row
+const errorKey = "error"
+
+var (
+	// emailPattern matches the email addresses in free text
+	emailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)
+	// keyValuePattern matches the columns & their values in the details of Postgres errors, e.g.
+	// Key (email)=(jane.doe@example.com) already exists
+	keyValuePattern = regexp.MustCompile(`\(([^()]+)\)=\(([^()]*)\)`)
+)
+
+// redactCore masks the values of the fields with the configured keys, before they're written
+type redactCore struct {
+	zapcore.Core
+	keys map[string]struct{}
+}
+
+func newRedactCore(core zapcore.Core, keys []string) zapcore.Core {
+	rc := &redactCore{
+		Core: core,
+		keys: make(map[string]struct{}, len(keys)),
+	}
+	for _, key := range keys {
+		rc.keys[strings.ToLower(key)] = struct{}{}
+	}
+
+	return rc
+}
+
+func (rc *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{
+		Core: rc.Core.With(rc.redact(fields)),
+		keys: rc.keys,
+	}
+}
+
+func (rc *redactCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if rc.Enabled(entry.Level) {
+		return checked.AddCore(entry, rc)
+	}
+
+	return checked
+}
+
+func (rc *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return rc.Core.Write(entry, rc.redact(fields))
+}
+
+func (rc *redactCore) redacted(key string) bool {
+	_, ok := rc.keys[strings.ToLower(key)]
+	return ok
+}
+
+// redact returns a copy of the fields, with the redacted ones masked. Maps of headers or values are
+// redacted by their keys as well, while errors are redacted by their text
+func (rc *redactCore) redact(fields []zapcore.Field) []zapcore.Field {
+	result := make([]zapcore.Field, 0, len(fields))
+	for _, field := range fields {
+		if rc.redacted(field.Key) {
+			result = append(result, zap.String(field.Key, Redacted))
+			continue
+		}
+
+		switch field.Type {
+		case zapcore.ErrorType:
+			result = append(result, rc.redactError(field)...)
+			continue
+		case zapcore.StringType:
+			if field.Key == errorKey {
+				field = zap.String(field.Key, rc.redactText(field.String))
+			}
+		case zapcore.ReflectType:
+			switch value := field.Interface.(type) {
+			case http.Header:
+				field = zap.Any(field.Key, redactMap(rc, map[string][]string(value)))
+			case map[string][]string:
+				field = zap.Any(field.Key, redactMap(rc, value))
+			case map[string]string:
+				field = zap.Any(field.Key, redactMap(rc, value))
+			case map[string]interface{}:
+				field = zap.Any(field.Key, redactMap(rc, value))
+			}
+		}
+
+		result = append(result, field)
+	}
+
+	return result
+}
+
+// redactError returns the redacted text of an error field, & its stack trace as <key>Verbose like
+// zap does, if the error has one
+func (rc *redactCore) redactError(field zapcore.Field) []zapcore.Field {
+	err, ok := field.Interface.(error)
+	if !ok || err == nil {
+		return []zapcore.Field{field}
+	}
+
+	message := err.Error()
+	result := []zapcore.Field{zap.String(field.Key, rc.redactText(message))}
+	if verbose := fmt.Sprintf("%+v", err); verbose != message {
+		result = append(result, zap.String(field.Key+"Verbose", rc.redactText(verbose)))
+	}
+
+	return result
+}
+
+// redactText masks the email addresses in free text, & the values of the redacted columns in the
+// details of Postgres errors
+func (rc *redactCore) redactText(text string) string {
+	text = keyValuePattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := keyValuePattern.FindStringSubmatch(match)
+		for _, column := range strings.Split(parts[1], ",") {
+			if rc.redacted(strings.TrimSpace(column)) {
+				return fmt.Sprintf("(%s)=(%s)", parts[1], Redacted)
+			}
+		}
+		return match
+	})
+
+	return emailPattern.ReplaceAllString(text, Redacted)
+}
+
+func redactMap[V any](rc *redactCore, m map[string]V) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		if rc.redacted(key) {
+			result[key] = Redacted
+			continue
+		}
+		result[key] = value
+	}
+
+	return result
+}
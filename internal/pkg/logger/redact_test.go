@@ -0,0 +1,111 @@
+package logger
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedactCore(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	l := zap.New(newRedactCore(core, DefaultRedactKeys)).Sugar()
+
+	l.With("password", "secret").Infow(
+		"user created",
+		"Email", "jane.doe@example.com",
+		"mobile", "+31600000000",
+		"userID", 42,
+		"headers", http.Header{"Authorization": {"Bearer token"}, "Accept": {"application/json"}},
+		"attributes", map[string]interface{}{"email": "jane.doe@example.com", "plan": "free"},
+	)
+
+	fields := logs.All()[0].ContextMap()
+	for _, key := range []string{"password", "Email", "mobile"} {
+		if fields[key] != Redacted {
+			t.Errorf("field %s = %v, want it redacted", key, fields[key])
+		}
+	}
+	if fields["userID"] != int64(42) {
+		t.Errorf("field userID = %v, want 42", fields["userID"])
+	}
+
+	headers := fields["headers"].(map[string]interface{})
+	if headers["Authorization"] != Redacted {
+		t.Errorf("Authorization header = %v, want it redacted", headers["Authorization"])
+	}
+	if accept, _ := headers["Accept"].([]string); len(accept) != 1 || accept[0] != "application/json" {
+		t.Errorf("Accept header = %v, want it as is", headers["Accept"])
+	}
+
+	attributes := fields["attributes"].(map[string]interface{})
+	if attributes["email"] != Redacted || attributes["plan"] != "free" {
+		t.Errorf("attributes = %v, want only the email redacted", attributes)
+	}
+}
+
+func TestRedactCore_error(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	l := zap.New(newRedactCore(core, DefaultRedactKeys)).Sugar()
+
+	// e.g. a unique violation of Postgres, wrapped by the persistence
+	pgErr := stderrors.New(`duplicate key value violates unique constraint "users_email_key" ` +
+		`(SQLSTATE 23505): Key (email)=(jane.doe@example.com) already exists, Key (id)=(42)`)
+	err := errors.InternalErr(pgErr, "failed to create user")
+
+	l.Errorw("request failed", "error", err)
+	l.Warnw("failed to cache", "error", "mongo: dup key: { email: \"john.doe@example.com\" }")
+
+	entries := logs.All()
+	if entries[0].Message != "request failed" {
+		t.Errorf("message = %q, want the fixed message", entries[0].Message)
+	}
+
+	fields := entries[0].ContextMap()
+	for _, key := range []string{"error", "errorVerbose"} {
+		text, _ := fields[key].(string)
+		if text == "" {
+			t.Fatalf("field %s is missing, got %v", key, fields)
+		}
+		if strings.Contains(text, "jane.doe") {
+			t.Errorf("field %s = %q, want the email redacted", key, text)
+		}
+		if !strings.Contains(text, "Key (email)=("+Redacted+")") || !strings.Contains(text, "Key (id)=(42)") {
+			t.Errorf("field %s = %q, want only the value of the email redacted", key, text)
+		}
+	}
+	if !strings.Contains(fields["errorVerbose"].(string), "TestRedactCore_error") {
+		t.Errorf("errorVerbose = %q, want the stack trace", fields["errorVerbose"])
+	}
+
+	text := entries[1].ContextMap()["error"].(string)
+	if strings.Contains(text, "john.doe") || !strings.Contains(text, Redacted) {
+		t.Errorf("error = %q, want the email redacted", text)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	defer SetLevel(InfoLevel)
+
+	for _, name := range []string{"debug", "info", "warn", "error"} {
+		lvl, err := ParseLevel(name)
+		if err != nil {
+			t.Fatalf("ParseLevel(%s) error = %v", name, err)
+		}
+
+		SetLevel(lvl)
+		if got := level.Level().String(); got != name {
+			t.Errorf("level = %s, want %s", got, name)
+		}
+	}
+
+	_, err := ParseLevel("verbose")
+	if err == nil {
+		t.Errorf("ParseLevel(verbose) expected an error")
+	}
+}
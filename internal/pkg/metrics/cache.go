@@ -0,0 +1,19 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var cacheRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Number of cache lookups, by cache & result (hit, miss or bypass)",
+	},
+	[]string{"cache", "result"},
+)
+
+// ObserveCacheRequest records a lookup of the cache, result is hit, miss, or bypass if the cache
+// was skipped on request
+func ObserveCacheRequest(cache string, result string) {
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests served, by route pattern, method & status code",
+		},
+		[]string{"route", "method", "status"},
+	)
+	httpRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Latency of the HTTP requests served, by route pattern, method & status code",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"route", "method", "status"},
+	)
+)
+
+// ObserveHTTPRequest records a request served. The route should be the pattern, e.g.
+// /api/v1/users/{id}, & not the path, so that the number of series is bounded
+func ObserveHTTPRequest(route string, method string, status int, latency time.Duration) {
+	if route == "" {
+		// requests which did not match any route, e.g. 404s, are grouped together
+		route = "unmatched"
+	}
+
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(route, method, code).Inc()
+	httpRequestDuration.WithLabelValues(route, method, code).Observe(latency.Seconds())
+}
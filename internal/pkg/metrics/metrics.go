@@ -0,0 +1,39 @@
+// Package metrics defines all the Prometheus metrics of the app, so that their names & labels are
+// in one place. The names are part of the contract with the dashboards & alerts, so they should
+// never be changed, only added.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registry has all the metrics of the app, along with the Go runtime & process metrics
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		mongoPoolConnections,
+		mongoPoolCheckedOut,
+		mongoPoolEvents,
+		openAIRequestDuration,
+		openAIErrors,
+		openAITokens,
+		openAIRetries,
+		openAICircuitState,
+		cacheRequests,
+	)
+}
+
+// Handler returns an HTTP handler which responds with all the metrics, in the Prometheus
+// exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
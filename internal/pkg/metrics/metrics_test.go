@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/circuitbreaker"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func TestHandler(t *testing.T) {
+	ObserveHTTPRequest("/api/v1/users/{id}", http.MethodGet, http.StatusOK, 20*time.Millisecond)
+	ObserveHTTPRequest("", http.MethodGet, http.StatusNotFound, time.Millisecond)
+	ObserveOpenAICall("chat_completion", time.Second, nil, 10, 90)
+	ObserveOpenAICall("chat_completion", time.Second, errors.New("timeout"), 0, 0)
+	ObserveOpenAIRetry("rate_limited")
+	SetOpenAICircuitState(circuitbreaker.StateOpen)
+	ObserveCacheRequest("completions", "hit")
+	observeMongoPoolEvent(&event.PoolEvent{Type: event.ConnectionCreated})
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/-/metrics", nil))
+
+	// the names are used by the dashboards, & should never change
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/api/v1/users/{id}",status="200"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="/api/v1/users/{id}",status="200",le="0.025"} 1`,
+		`openai_request_duration_seconds_count{operation="chat_completion",result="success"} 1`,
+		`openai_errors_total{operation="chat_completion"} 1`,
+		`openai_tokens_total{operation="chat_completion",type="completion"} 90`,
+		`openai_retries_total{reason="rate_limited"} 1`,
+		`openai_circuit_breaker_state{state="open"} 1`,
+		`openai_circuit_breaker_state{state="closed"} 0`,
+		`cache_requests_total{cache="completions",result="hit"} 1`,
+		`mongodb_pool_connections 1`,
+		`mongodb_pool_events_total{type="ConnectionCreated"} 1`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("expected '%s' in the metrics", want)
+		}
+	}
+}
+
+func TestObserveMongoPoolEvent(t *testing.T) {
+	before := testutil.ToFloat64(mongoPoolCheckedOut)
+
+	observeMongoPoolEvent(&event.PoolEvent{Type: event.GetSucceeded})
+	observeMongoPoolEvent(&event.PoolEvent{Type: event.GetSucceeded})
+	observeMongoPoolEvent(&event.PoolEvent{Type: event.ConnectionReturned})
+	observeMongoPoolEvent(&event.PoolEvent{Type: event.GetFailed})
+
+	if got := testutil.ToFloat64(mongoPoolCheckedOut) - before; got != 1 {
+		t.Errorf("checked out connections = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(mongoPoolEvents.WithLabelValues(event.GetFailed)); got != 1 {
+		t.Errorf("check out failures = %v, want 1", got)
+	}
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+var (
+	mongoPoolConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "mongodb_pool_connections",
+		Help: "Number of connections in the MongoDB pools",
+	})
+	mongoPoolCheckedOut = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "mongodb_pool_checked_out_connections",
+		Help: "Number of connections currently checked out of the MongoDB pools",
+	})
+	mongoPoolEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mongodb_pool_events_total",
+			Help: "Number of MongoDB pool events, by type, e.g. ConnectionCheckOutFailed",
+		},
+		[]string{"type"},
+	)
+)
+
+// MongoPoolMonitor returns a pool monitor of the MongoDB driver, which records the pool events
+func MongoPoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: observeMongoPoolEvent,
+	}
+}
+
+func observeMongoPoolEvent(e *event.PoolEvent) {
+	mongoPoolEvents.WithLabelValues(e.Type).Inc()
+
+	switch e.Type {
+	case event.ConnectionCreated:
+		mongoPoolConnections.Inc()
+	case event.ConnectionClosed:
+		mongoPoolConnections.Dec()
+	case event.GetSucceeded:
+		mongoPoolCheckedOut.Inc()
+	case event.ConnectionReturned:
+		mongoPoolCheckedOut.Dec()
+	}
+}
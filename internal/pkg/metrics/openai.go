@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/circuitbreaker"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	openAIRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "openai_request_duration_seconds",
+			Help:    "Latency of the OpenAI API calls, by operation & result (success or error)",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60},
+		},
+		[]string{"operation", "result"},
+	)
+	openAIErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "openai_errors_total",
+			Help: "Number of failed OpenAI API calls, by operation",
+		},
+		[]string{"operation"},
+	)
+	openAITokens = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "openai_tokens_total",
+			Help: "Number of tokens used by the OpenAI API calls, by operation & type (prompt or completion)",
+		},
+		[]string{"operation", "type"},
+	)
+	openAIRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "openai_retries_total",
+			Help: "Number of retried OpenAI API requests, by reason (rate_limited, server_error or network_error)",
+		},
+		[]string{"reason"},
+	)
+	openAICircuitState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "openai_circuit_breaker_state",
+			Help: "State of the circuit breaker of the OpenAI API calls, 1 for the current state (closed, half-open or open)",
+		},
+		[]string{"state"},
+	)
+)
+
+// ObserveOpenAICall records a call to the OpenAI API, & the tokens it used
+func ObserveOpenAICall(operation string, latency time.Duration, err error, promptTokens int, completionTokens int) {
+	result := "success"
+	if err != nil {
+		result = "error"
+		openAIErrors.WithLabelValues(operation).Inc()
+	}
+
+	openAIRequestDuration.WithLabelValues(operation, result).Observe(latency.Seconds())
+	openAITokens.WithLabelValues(operation, "prompt").Add(float64(promptTokens))
+	openAITokens.WithLabelValues(operation, "completion").Add(float64(completionTokens))
+}
+
+// ObserveOpenAIRetry records a retry of a request to the OpenAI API
+func ObserveOpenAIRetry(reason string) {
+	openAIRetries.WithLabelValues(reason).Inc()
+}
+
+// SetOpenAICircuitState records the current state of the circuit breaker of the OpenAI API calls
+func SetOpenAICircuitState(state string) {
+	for _, s := range []string{circuitbreaker.StateClosed, circuitbreaker.StateHalfOpen, circuitbreaker.StateOpen} {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		openAICircuitState.WithLabelValues(s).Set(value)
+	}
+}
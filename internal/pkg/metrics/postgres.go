@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	pgxpoolAcquiredDesc = prometheus.NewDesc(
+		"pgxpool_acquired_connections",
+		"Number of connections currently acquired from the Postgres pool",
+		nil, nil,
+	)
+	pgxpoolIdleDesc = prometheus.NewDesc(
+		"pgxpool_idle_connections",
+		"Number of idle connections in the Postgres pool",
+		nil, nil,
+	)
+	pgxpoolTotalDesc = prometheus.NewDesc(
+		"pgxpool_total_connections",
+		"Number of connections in the Postgres pool, including the ones being established",
+		nil, nil,
+	)
+	pgxpoolMaxDesc = prometheus.NewDesc(
+		"pgxpool_max_connections",
+		"Maximum number of connections of the Postgres pool",
+		nil, nil,
+	)
+	pgxpoolConstructingDesc = prometheus.NewDesc(
+		"pgxpool_constructing_connections",
+		"Number of connections of the Postgres pool being established, acquires wait for them",
+		nil, nil,
+	)
+	// pgxpool has no count of the acquires currently waiting for a connection, so the waits are
+	// counted instead, as the substitute of a gauge of the waiting connections
+	pgxpoolEmptyAcquireDesc = prometheus.NewDesc(
+		"pgxpool_empty_acquire_total",
+		"Number of acquires which waited for a connection, because the Postgres pool was empty",
+		nil, nil,
+	)
+	pgxpoolAcquireDurationDesc = prometheus.NewDesc(
+		"pgxpool_acquire_duration_seconds_total",
+		"Total time spent acquiring connections from the Postgres pool, including the waits",
+		nil, nil,
+	)
+)
+
+// pgxpoolCollector reads the stats of the pool when the metrics are collected
+type pgxpoolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func (pc *pgxpoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pgxpoolAcquiredDesc
+	ch <- pgxpoolIdleDesc
+	ch <- pgxpoolTotalDesc
+	ch <- pgxpoolMaxDesc
+	ch <- pgxpoolConstructingDesc
+	ch <- pgxpoolEmptyAcquireDesc
+	ch <- pgxpoolAcquireDurationDesc
+}
+
+func (pc *pgxpoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := pc.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(pgxpoolAcquiredDesc, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(pgxpoolIdleDesc, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(pgxpoolTotalDesc, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(pgxpoolMaxDesc, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(pgxpoolConstructingDesc, prometheus.GaugeValue, float64(stat.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(pgxpoolEmptyAcquireDesc, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(pgxpoolAcquireDurationDesc, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
+
+// RegisterPgxPool adds the stats of the Postgres pool to the metrics. Only one pool can be
+// registered
+func RegisterPgxPool(pool *pgxpool.Pool) error {
+	return registry.Register(&pgxpoolCollector{pool: pool})
+}
@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// mongoCollectionKey is the attribute with the name of the collection of the command
+const mongoCollectionKey = attribute.Key("db.mongodb.collection")
+
+// mongoTracer traces the commands of the MongoDB driver. The driver notifies the start & the end of
+// the commands separately, so the spans are kept by request ID until the command ends
+type mongoTracer struct {
+	spans sync.Map
+}
+
+// MongoCommandMonitor returns a command monitor of the MongoDB driver, which traces every command
+// as a child span of the span in the context of the command. The commands are not recorded, as
+// they may have personal data
+func MongoCommandMonitor() *event.CommandMonitor {
+	mt := &mongoTracer{}
+
+	return &event.CommandMonitor{
+		Started:   mt.started,
+		Succeeded: mt.succeeded,
+		Failed:    mt.failed,
+	}
+}
+
+func (mt *mongoTracer) started(ctx context.Context, e *event.CommandStartedEvent) {
+	attrs := dbAttributes("mongodb", e.CommandName, "")
+	// the value of the command name is the collection, for the collection level commands
+	if collection, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+		attrs = append(attrs, mongoCollectionKey.String(collection))
+	}
+
+	_, span := Start(
+		ctx,
+		"mongodb "+e.CommandName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	mt.spans.Store(e.RequestID, span)
+}
+
+func (mt *mongoTracer) succeeded(_ context.Context, e *event.CommandSucceededEvent) {
+	mt.end(e.RequestID, nil)
+}
+
+func (mt *mongoTracer) failed(_ context.Context, e *event.CommandFailedEvent) {
+	mt.end(e.RequestID, errors.Internal(e.Failure))
+}
+
+func (mt *mongoTracer) end(requestID int64, err error) {
+	value, ok := mt.spans.LoadAndDelete(requestID)
+	if !ok {
+		return
+	}
+
+	span := value.(trace.Span)
+	RecordError(span, err)
+	span.End()
+}
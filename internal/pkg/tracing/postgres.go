@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PgxTracer traces every query of pgx, as a child span of the span in the context of the query.
+// The SQL statement is recorded, but not its arguments, as they may have personal data
+type PgxTracer struct{}
+
+func (PgxTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := sqlOperation(data.SQL)
+	ctx, _ = Start(
+		ctx,
+		"postgres "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbAttributes("postgresql", operation, data.SQL)...),
+	)
+
+	return ctx
+}
+
+func (PgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	RecordError(span, data.Err)
+	span.End()
+}
+
+// sqlOperation returns the operation of the SQL statement, i.e. its first keyword
+func sqlOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+
+	return strings.ToUpper(fields[0])
+}
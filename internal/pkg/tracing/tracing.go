@@ -0,0 +1,132 @@
+// Package tracing sets up OpenTelemetry tracing, & has the helpers to trace the components of the
+// app. Spans are exported to stdout or to an OTLP collector, so that tracing works offline as well.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ExporterNone disables exporting the spans, the trace context is still propagated
+	ExporterNone = "none"
+	// ExporterStdout writes the spans to stdout, useful for local development
+	ExporterStdout = "stdout"
+	// ExporterOTLP sends the spans to an OTLP collector over HTTP
+	ExporterOTLP = "otlp"
+
+	instrumentationName = "github.com/mohamedveron/go_app_template"
+)
+
+// Config is the configuration of tracing
+type Config struct {
+	ServiceName    string
+	ServiceVersion string
+	// Exporter is one of the Exporter* values
+	Exporter string
+	// OTLPEndpoint is the host & port of the OTLP collector, e.g. localhost:4318
+	OTLPEndpoint string
+	// OTLPInsecure sends the spans over HTTP, instead of HTTPS
+	OTLPInsecure bool
+	// SampleRatio is the ratio of the traces sampled, from 0 to 1. The sampling decision of the
+	// caller is honored
+	SampleRatio float64
+}
+
+// Setup sets the global tracer provider & the W3C trace context propagator. The returned function
+// flushes the pending spans & stops the exporter
+func Setup(ctx context.Context, cfg *Config) (shutdown func(ctx context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	switch strings.ToLower(cfg.Exporter) {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.OTLPEndpoint)}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, errors.Validation(fmt.Sprintf("unknown tracing exporter '%s'", cfg.Exporter))
+	}
+	if err != nil {
+		return nil, errors.InternalErr(err, "failed to create the tracing exporter")
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(cfg.ServiceVersion),
+	))
+	if err != nil {
+		return nil, errors.InternalErr(err, "failed to create the tracing resource")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of the span in ctx, if any. The span has to be ended by the caller
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// RecordError marks the span as failed with err, if err is not nil
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// IDs returns the trace & span IDs of the span in ctx, they're empty if there's no valid span
+func IDs(ctx context.Context) (traceID string, spanID string) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", ""
+	}
+
+	return sc.TraceID().String(), sc.SpanID().String()
+}
+
+// dbAttributes are the attributes common to all the database spans, statement is skipped if empty
+func dbAttributes(system string, operation string, statement string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemKey.String(system),
+		semconv.DBOperation(operation),
+	}
+	if statement != "" {
+		attrs = append(attrs, semconv.DBStatement(statement))
+	}
+
+	return attrs
+}
@@ -0,0 +1,146 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newRecorder() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter string
+		wantErr  bool
+	}{
+		{name: "none", exporter: ExporterNone},
+		{name: "stdout", exporter: ExporterStdout},
+		{name: "otlp", exporter: ExporterOTLP},
+		{name: "unknown", exporter: "jaeger", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), &Config{
+				ServiceName:  "go_app",
+				Exporter:     tt.exporter,
+				OTLPEndpoint: "localhost:4318",
+				OTLPInsecure: true,
+				SampleRatio:  1,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Setup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				_ = shutdown(context.Background())
+			}
+		})
+	}
+}
+
+func TestIDs(t *testing.T) {
+	newRecorder()
+
+	traceID, spanID := IDs(context.Background())
+	if traceID != "" || spanID != "" {
+		t.Errorf("expected no IDs without a span, got %s %s", traceID, spanID)
+	}
+
+	ctx, span := Start(context.Background(), "test")
+	defer span.End()
+	traceID, spanID = IDs(ctx)
+	if traceID != span.SpanContext().TraceID().String() || spanID != span.SpanContext().SpanID().String() {
+		t.Errorf("IDs() = %s %s, want the IDs of the span", traceID, spanID)
+	}
+}
+
+func TestPgxTracer(t *testing.T) {
+	recorder := newRecorder()
+	ctx, parent := Start(context.Background(), "parent")
+
+	tracer := PgxTracer{}
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: " select id from users where id = $1"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{Err: pgx.ErrNoRows})
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	query := spans[0]
+	if query.Name() != "postgres SELECT" {
+		t.Errorf("span name = %s, want 'postgres SELECT'", query.Name())
+	}
+	if query.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("query span should be a child of the span in the context")
+	}
+	if query.Status().Code != codes.Error {
+		t.Errorf("expected the query error to be recorded, got %v", query.Status())
+	}
+}
+
+func TestMongoCommandMonitor(t *testing.T) {
+	recorder := newRecorder()
+	ctx, parent := Start(context.Background(), "parent")
+	defer parent.End()
+
+	command, err := bson.Marshal(bson.D{{Key: "find", Value: "users"}, {Key: "filter", Value: bson.D{{Key: "email", Value: "jane.doe@example.com"}}}})
+	if err != nil {
+		t.Fatalf("failed to marshal command: %v", err)
+	}
+
+	monitor := MongoCommandMonitor()
+	monitor.Started(ctx, &event.CommandStartedEvent{Command: command, CommandName: "find", RequestID: 1})
+	monitor.Started(ctx, &event.CommandStartedEvent{Command: command, CommandName: "find", RequestID: 2})
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{RequestID: 1}})
+	monitor.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{RequestID: 2}, Failure: "timeout"})
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	for _, span := range spans {
+		if span.Name() != "mongodb find" {
+			t.Errorf("span name = %s, want 'mongodb find'", span.Name())
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("command span should be a child of the span in the context")
+		}
+		for _, attr := range span.Attributes() {
+			if attr.Key == mongoCollectionKey && attr.Value.AsString() != "users" {
+				t.Errorf("collection = %s, want users", attr.Value.AsString())
+			}
+			if attr.Value.AsString() == "jane.doe@example.com" {
+				t.Errorf("the command should not be recorded")
+			}
+		}
+	}
+	if spans[0].Status().Code == codes.Error || spans[1].Status().Code != codes.Error {
+		t.Errorf("expected only the failed command to be an error, got %v & %v", spans[0].Status(), spans[1].Status())
+	}
+}
+
+func TestRecordError(t *testing.T) {
+	recorder := newRecorder()
+	_, span := Start(context.Background(), "test")
+	RecordError(span, nil)
+	RecordError(span, errors.Internal("failed"))
+	span.End()
+
+	got := recorder.Ended()[0].Status()
+	if got.Code != codes.Error || got.Description != "failed" {
+		t.Errorf("status = %v, want error 'failed'", got)
+	}
+}
@@ -0,0 +1,54 @@
+package users
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/users/domain"
+)
+
+const (
+	// DefaultListLimit is the number of users in a page, when no limit is provided
+	DefaultListLimit = 20
+	// MaxListLimit is the maximum number of users in a page, larger limits are capped to it
+	MaxListLimit = 100
+)
+
+// cursorToken is the content of the opaque cursor returned to the clients. The sort order is
+// included, so that a cursor cannot be used to resume a list sorted differently
+type cursorToken struct {
+	OrderBy domain.UsersOrderBy `json:"o"`
+	domain.UserCursor
+}
+
+func encodeCursor(cursor *domain.UserCursor, orderBy domain.UsersOrderBy) (string, error) {
+	raw, err := json.Marshal(cursorToken{
+		OrderBy:    orderBy,
+		UserCursor: *cursor,
+	})
+	if err != nil {
+		return "", errors.InternalErr(err, "failed to encode cursor")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeCursor(cursor string, orderBy domain.UsersOrderBy) (*domain.UserCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.ValidationErr(err, "invalid cursor")
+	}
+
+	token := cursorToken{}
+	err = json.Unmarshal(raw, &token)
+	if err != nil {
+		return nil, errors.ValidationErr(err, "invalid cursor")
+	}
+
+	if token.OrderBy != orderBy {
+		return nil, errors.Validation("cursor does not match orderBy")
+	}
+
+	return &token.UserCursor, nil
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"time"
+)
+
+// UsersOrderBy is the field by which users are sorted when listing. Users are always sorted in
+// ascending order, with the ID as the tie breaker
+type UsersOrderBy string
+
+const (
+	UsersOrderByID        UsersOrderBy = "id"
+	UsersOrderByCreatedAt UsersOrderBy = "createdAt"
+)
+
+// UserCursor is the position of a user in a sorted list of users, listing resumes after it
+type UserCursor struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// UsersQuery holds the filters, sort order & page of users to list
+type UsersQuery struct {
+	OrderBy UsersOrderBy
+	// EmailDomain matches users whose email is of the given domain, e.g. example.com
+	EmailDomain string
+	// NamePrefix matches users whose first or last name starts with the prefix, case insensitive
+	NamePrefix string
+	// CreatedFrom matches users created at or after the given time
+	CreatedFrom *time.Time
+	// CreatedTo matches users created before the given time
+	CreatedTo *time.Time
+	// After is the cursor of the last user of the previous page, nil for the first page
+	After *UserCursor
+	Limit int
+}
+
+// UsersPage is a single page of users
+type UsersPage struct {
+	Users []*User
+	// NextCursor is an opaque token to get the next page, empty if this is the last page
+	NextCursor string
+}
+
+// Cursor returns the position of the user in a sorted list of users
+func (u *User) Cursor() *UserCursor {
+	cursor := &UserCursor{
+		ID: u.ID,
+	}
+	if u.CreatedAt != nil {
+		cursor.CreatedAt = *u.CreatedAt
+	}
+
+	return cursor
+}
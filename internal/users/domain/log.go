@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// MarshalLogObject logs only the fields of the user which are not personal data, so that they never
+// appear in the logs in clear text
+func (u User) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt64("id", u.ID)
+	enc.AddInt64("version", u.Version)
+	if u.CreatedAt != nil {
+		enc.AddTime("createdAt", *u.CreatedAt)
+	}
+	if u.UpdatedAt != nil {
+		enc.AddTime("updatedAt", *u.UpdatedAt)
+	}
+
+	return nil
+}
+
+// String returns the user without its personal data, it's what's logged when formatting the user
+func (u User) String() string {
+	return fmt.Sprintf("User{ID: %d, Version: %d}", u.ID, u.Version)
+}
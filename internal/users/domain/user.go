@@ -1,19 +1,31 @@
 package domain
 
 import (
-	"errors"
 	"strings"
 	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
 )
 
 // User holds all data required to represent a user
 type User struct {
+	ID        int64      `json:"id,omitempty"`
 	FirstName string     `json:"firstName,omitempty"`
 	LastName  string     `json:"lastName,omitempty"`
 	Mobile    string     `json:"mobile,omitempty"`
 	Email     string     `json:"email,omitempty"`
 	CreatedAt *time.Time `json:"createdAt,omitempty"`
 	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	// Version is incremented on every update, & is used to detect concurrent updates
+	Version int64 `json:"version,omitempty"`
+}
+
+// UserPatch holds the changes to be applied to a User, nil fields are left unchanged
+type UserPatch struct {
+	FirstName *string
+	LastName  *string
+	Mobile    *string
+	Email     *string
 }
 
 func (u *User) SetDefaults() {
@@ -25,6 +37,32 @@ func (u *User) SetDefaults() {
 	if u.UpdatedAt == nil {
 		u.UpdatedAt = &now
 	}
+
+	if u.Version == 0 {
+		u.Version = 1
+	}
+}
+
+// ApplyPatch applies all the non-nil fields of the patch on User
+func (u *User) ApplyPatch(p *UserPatch) {
+	if p.FirstName != nil {
+		u.FirstName = *p.FirstName
+	}
+	if p.LastName != nil {
+		u.LastName = *p.LastName
+	}
+	if p.Mobile != nil {
+		u.Mobile = *p.Mobile
+	}
+	if p.Email != nil {
+		u.Email = *p.Email
+	}
+}
+
+// Touch sets UpdatedAt to the current time
+func (u *User) Touch() {
+	now := time.Now()
+	u.UpdatedAt = &now
 }
 
 // Sanitize is used to sanitize/cleanup the fields of User
@@ -35,8 +73,13 @@ func (u *User) Sanitize() {
 	u.Mobile = strings.TrimSpace(u.Mobile)
 }
 
-// Validate is used to validate the fields of User
+// Validate is used to validate the fields of User. The name is required, as the first name, while
+// the email & the mobile are optional
 func (u *User) Validate() error {
+	if u.FirstName == "" {
+		return errors.Validation("name is required")
+	}
+
 	if u.Email == "" {
 		return nil
 	}
@@ -52,7 +95,7 @@ func (u *User) Validate() error {
 func (u *User) ValidateEmail(email string) error {
 	parts := strings.Split(email, "@")
 	if len(parts) != 2 {
-		return errors.New("invalid email address provided")
+		return errors.Validation("invalid email address provided")
 	}
 
 	return nil
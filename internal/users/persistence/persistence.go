@@ -3,10 +3,46 @@ package persistence
 import (
 	"context"
 
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
 	"github.com/mohamedveron/go_app_template/internal/users/domain"
 )
 
 type UsersPersistence interface {
 	Create(ctx context.Context, u *domain.User) error
+	ReadByID(ctx context.Context, id int64) (*domain.User, error)
 	ReadByEmail(ctx context.Context, email string) (*domain.User, error)
+	// Update updates all the fields of an existing user, except ID & CreatedAt, only if its stored
+	// version is u.Version. The version is incremented on success
+	Update(ctx context.Context, u *domain.User) error
+	Delete(ctx context.Context, id int64) error
+	// List returns at most query.Limit users matching the query, sorted by query.OrderBy & then ID,
+	// starting after query.After
+	List(ctx context.Context, query *domain.UsersQuery) ([]*domain.User, error)
+}
+
+// The following errors are returned by every implementation of UsersPersistence, so that the
+// behaviour is the same irrespective of the datastore.
+
+// errUserNotFound is returned when there's no user with the given ID
+func errUserNotFound(err error) error {
+	return errors.NotFoundErr(err, "user not found")
+}
+
+// errEmailNotFound is returned when there's no user with the given email
+func errEmailNotFound(err error) error {
+	return errors.NotFoundErr(err, "email not found")
+}
+
+// errEmailExists is returned when a user with the same email already exists. The error of the
+// datastore is not wrapped, since it may have the email, which is kept out of the responses & logs
+func errEmailExists(ctx context.Context) error {
+	logger.FromContext(ctx).Info("user rejected, email already exists")
+	return errors.Conflict("email already in use")
+}
+
+// errVersionMismatch is returned when the user was updated after it was read
+func errVersionMismatch(ctx context.Context, version int64) error {
+	logger.FromContext(ctx).Infow("user update rejected, version mismatch", "version", version)
+	return errors.PreconditionFailed("user was modified by another request")
 }
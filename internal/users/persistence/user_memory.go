@@ -0,0 +1,204 @@
+package persistence
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/users/domain"
+)
+
+// UserMemoryPersistence is an in-memory implementation of UsersPersistence, to be used for tests
+// & local development. All data is lost when the app stops
+type UserMemoryPersistence struct {
+	lock   *sync.RWMutex
+	lastID int64
+	users  map[int64]*domain.User
+	// emails maps the email of every user to its ID, to enforce unique emails. Users without an
+	// email are not in it, since any number of them can be without one
+	emails map[string]int64
+}
+
+func (um *UserMemoryPersistence) Create(ctx context.Context, u *domain.User) error {
+	um.lock.Lock()
+	defer um.lock.Unlock()
+
+	if _, exists := um.emails[u.Email]; exists && u.Email != "" {
+		return errEmailExists(ctx)
+	}
+
+	um.lastID++
+	u.ID = um.lastID
+	um.users[u.ID] = copyUser(u)
+	if u.Email != "" {
+		um.emails[u.Email] = u.ID
+	}
+
+	return nil
+}
+
+func (um *UserMemoryPersistence) ReadByID(_ context.Context, id int64) (*domain.User, error) {
+	um.lock.RLock()
+	defer um.lock.RUnlock()
+
+	u, ok := um.users[id]
+	if !ok {
+		return nil, errUserNotFound(nil)
+	}
+
+	return copyUser(u), nil
+}
+
+func (um *UserMemoryPersistence) ReadByEmail(_ context.Context, email string) (*domain.User, error) {
+	um.lock.RLock()
+	defer um.lock.RUnlock()
+
+	id, ok := um.emails[email]
+	if !ok || email == "" {
+		return nil, errEmailNotFound(nil)
+	}
+
+	return copyUser(um.users[id]), nil
+}
+
+func (um *UserMemoryPersistence) Update(ctx context.Context, u *domain.User) error {
+	um.lock.Lock()
+	defer um.lock.Unlock()
+
+	existing, ok := um.users[u.ID]
+	if !ok {
+		return errUserNotFound(nil)
+	}
+
+	if existing.Version != u.Version {
+		return errVersionMismatch(ctx, u.Version)
+	}
+
+	if id, exists := um.emails[u.Email]; exists && id != u.ID && u.Email != "" {
+		return errEmailExists(ctx)
+	}
+
+	u.Version++
+	updated := copyUser(u)
+	updated.CreatedAt = existing.CreatedAt
+	um.users[u.ID] = updated
+
+	delete(um.emails, existing.Email)
+	if u.Email != "" {
+		um.emails[u.Email] = u.ID
+	}
+
+	return nil
+}
+
+func (um *UserMemoryPersistence) Delete(_ context.Context, id int64) error {
+	um.lock.Lock()
+	defer um.lock.Unlock()
+
+	existing, ok := um.users[id]
+	if !ok {
+		return errUserNotFound(nil)
+	}
+
+	delete(um.users, id)
+	delete(um.emails, existing.Email)
+
+	return nil
+}
+
+func (um *UserMemoryPersistence) List(_ context.Context, query *domain.UsersQuery) ([]*domain.User, error) {
+	um.lock.RLock()
+	defer um.lock.RUnlock()
+
+	list := make([]*domain.User, 0, len(um.users))
+	for _, u := range um.users {
+		if matchesQuery(u, query) {
+			list = append(list, u)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return isBefore(list[i], list[j], query.OrderBy)
+	})
+
+	if len(list) > query.Limit {
+		list = list[:query.Limit]
+	}
+
+	for i, u := range list {
+		list[i] = copyUser(u)
+	}
+
+	return list, nil
+}
+
+// matchesQuery reports whether the user matches all the filters of the query & is after its cursor
+func matchesQuery(u *domain.User, query *domain.UsersQuery) bool {
+	if query.EmailDomain != "" &&
+		!strings.HasSuffix(strings.ToLower(u.Email), "@"+strings.ToLower(query.EmailDomain)) {
+		return false
+	}
+
+	if query.NamePrefix != "" {
+		prefix := strings.ToLower(query.NamePrefix)
+		if !strings.HasPrefix(strings.ToLower(u.FirstName), prefix) &&
+			!strings.HasPrefix(strings.ToLower(u.LastName), prefix) {
+			return false
+		}
+	}
+
+	createdAt := time.Time{}
+	if u.CreatedAt != nil {
+		createdAt = *u.CreatedAt
+	}
+	if query.CreatedFrom != nil && createdAt.Before(*query.CreatedFrom) {
+		return false
+	}
+	if query.CreatedTo != nil && !createdAt.Before(*query.CreatedTo) {
+		return false
+	}
+
+	if query.After != nil {
+		after := &domain.User{ID: query.After.ID, CreatedAt: &query.After.CreatedAt}
+		return isBefore(after, u, query.OrderBy)
+	}
+
+	return true
+}
+
+// isBefore reports whether a is before b, when sorted by the given field & then ID
+func isBefore(a, b *domain.User, orderBy domain.UsersOrderBy) bool {
+	if orderBy == domain.UsersOrderByCreatedAt {
+		ac, bc := a.Cursor().CreatedAt, b.Cursor().CreatedAt
+		if !ac.Equal(bc) {
+			return ac.Before(bc)
+		}
+	}
+
+	return a.ID < b.ID
+}
+
+// copyUser returns a copy of u, so that the stored users cannot be modified by the callers
+func copyUser(u *domain.User) *domain.User {
+	cp := *u
+	if u.CreatedAt != nil {
+		createdAt := *u.CreatedAt
+		cp.CreatedAt = &createdAt
+	}
+	if u.UpdatedAt != nil {
+		updatedAt := *u.UpdatedAt
+		cp.UpdatedAt = &updatedAt
+	}
+
+	return &cp
+}
+
+func NewUserMemoryPersistence() *UserMemoryPersistence {
+	return &UserMemoryPersistence{
+		lock:   &sync.RWMutex{},
+		users:  map[int64]*domain.User{},
+		emails: map[string]int64{},
+	}
+}
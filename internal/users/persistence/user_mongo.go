@@ -1,18 +1,322 @@
 package persistence
 
 import (
+	"context"
+	"regexp"
+	"time"
+
 	"github.com/mohamedveron/go_app_template/internal/pkg/datastore"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/users/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const UserCollection = "users"
 
+const (
+	// emailIndex is the unique index of the emails. Only non-empty emails are indexed, since any
+	// number of users can be without an email
+	emailIndex = "email_unique_nonempty"
+	// legacyEmailIndex is the previous unique index of the emails, which indexed the empty emails too
+	legacyEmailIndex = "email_unique"
+	// mongoIndexNotFound is the MongoDB error code for dropping an index which doesn't exist
+	mongoIndexNotFound = 27
+)
+
+// CounterCollection holds the sequences used to generate numeric IDs, so that IDs are consistent
+// with the Postgres implementation
+const CounterCollection = "counters"
+
+// userDocument is the representation of domain.User in MongoDB
+type userDocument struct {
+	ID        int64      `bson:"_id"`
+	FirstName string     `bson:"firstName"`
+	LastName  string     `bson:"lastName"`
+	Mobile    string     `bson:"mobile"`
+	Email     string     `bson:"email"`
+	CreatedAt *time.Time `bson:"createdAt"`
+	UpdatedAt *time.Time `bson:"updatedAt"`
+	Version   int64      `bson:"version"`
+}
+
+func (ud *userDocument) toDomain() *domain.User {
+	u := &domain.User{
+		ID:        ud.ID,
+		FirstName: ud.FirstName,
+		LastName:  ud.LastName,
+		Mobile:    ud.Mobile,
+		Email:     ud.Email,
+		CreatedAt: ud.CreatedAt,
+		UpdatedAt: ud.UpdatedAt,
+		Version:   ud.Version,
+	}
+
+	// documents created before versioning was introduced are at the first version
+	if u.Version == 0 {
+		u.Version = 1
+	}
+
+	return u
+}
+
+func newUserDocument(u *domain.User) *userDocument {
+	return &userDocument{
+		ID:        u.ID,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Mobile:    u.Mobile,
+		Email:     u.Email,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+		Version:   u.Version,
+	}
+}
+
 type UserMongoPersistence struct {
 	collection *mongo.Collection
+	counters   *mongo.Collection
 }
 
-func NewUserMongoPersistence(mongodbCli *datastore.MongoDB) *UserMongoPersistence {
-	return &UserMongoPersistence{
+func (um *UserMongoPersistence) Create(ctx context.Context, u *domain.User) error {
+	id, err := um.nextID(ctx)
+	if err != nil {
+		return err
+	}
+
+	doc := newUserDocument(u)
+	doc.ID = id
+
+	_, err = um.collection.InsertOne(ctx, doc)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errEmailExists(ctx)
+		}
+		return errors.InternalErr(err, "failed to create user")
+	}
+
+	u.ID = id
+
+	return nil
+}
+
+func (um *UserMongoPersistence) ReadByID(ctx context.Context, id int64) (*domain.User, error) {
+	u, err := um.readOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errUserNotFound(err)
+		}
+
+		return nil, errors.InternalErr(err, "failed to read user")
+	}
+
+	return u, nil
+}
+
+func (um *UserMongoPersistence) ReadByEmail(ctx context.Context, email string) (*domain.User, error) {
+	if email == "" {
+		// the users without an email are stored with an empty email, none of them is matched
+		return nil, errEmailNotFound(nil)
+	}
+
+	u, err := um.readOne(ctx, bson.M{"email": email})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errEmailNotFound(err)
+		}
+
+		return nil, errors.InternalErr(err, "failed to read user")
+	}
+
+	return u, nil
+}
+
+func (um *UserMongoPersistence) Update(ctx context.Context, u *domain.User) error {
+	version := interface{}(u.Version)
+	if u.Version == 1 {
+		// documents created before versioning was introduced do not have a version
+		version = bson.M{"$in": bson.A{1, nil}}
+	}
+
+	result, err := um.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": u.ID, "version": version},
+		bson.M{
+			"$set": bson.M{
+				"firstName": u.FirstName,
+				"lastName":  u.LastName,
+				"mobile":    u.Mobile,
+				"email":     u.Email,
+				"updatedAt": u.UpdatedAt,
+				"version":   u.Version + 1,
+			},
+		},
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errEmailExists(ctx)
+		}
+		return errors.InternalErr(err, "failed to update user")
+	}
+
+	if result.MatchedCount == 0 {
+		// either the user does not exist, or its version has changed
+		_, err = um.ReadByID(ctx, u.ID)
+		if err != nil {
+			return err
+		}
+		return errVersionMismatch(ctx, u.Version)
+	}
+
+	u.Version++
+
+	return nil
+}
+
+func (um *UserMongoPersistence) Delete(ctx context.Context, id int64) error {
+	result, err := um.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return errors.InternalErr(err, "failed to delete user")
+	}
+
+	if result.DeletedCount == 0 {
+		return errUserNotFound(nil)
+	}
+
+	return nil
+}
+
+func (um *UserMongoPersistence) List(ctx context.Context, query *domain.UsersQuery) ([]*domain.User, error) {
+	where := bson.A{}
+	if query.EmailDomain != "" {
+		where = append(where, bson.M{"email": primitive.Regex{
+			Pattern: "@" + regexp.QuoteMeta(query.EmailDomain) + "$",
+			Options: "i",
+		}})
+	}
+	if query.NamePrefix != "" {
+		prefix := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query.NamePrefix), Options: "i"}
+		where = append(where, bson.M{"$or": bson.A{
+			bson.M{"firstName": prefix},
+			bson.M{"lastName": prefix},
+		}})
+	}
+	if query.CreatedFrom != nil {
+		where = append(where, bson.M{"createdAt": bson.M{"$gte": *query.CreatedFrom}})
+	}
+	if query.CreatedTo != nil {
+		where = append(where, bson.M{"createdAt": bson.M{"$lt": *query.CreatedTo}})
+	}
+
+	sort := bson.D{{Key: "_id", Value: 1}}
+	if query.OrderBy == domain.UsersOrderByCreatedAt {
+		sort = bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}
+		if query.After != nil {
+			where = append(where, bson.M{"$or": bson.A{
+				bson.M{"createdAt": bson.M{"$gt": query.After.CreatedAt}},
+				bson.M{"createdAt": query.After.CreatedAt, "_id": bson.M{"$gt": query.After.ID}},
+			}})
+		}
+	} else if query.After != nil {
+		where = append(where, bson.M{"_id": bson.M{"$gt": query.After.ID}})
+	}
+
+	filter := bson.M{}
+	if len(where) > 0 {
+		filter["$and"] = where
+	}
+
+	cursor, err := um.collection.Find(
+		ctx,
+		filter,
+		options.Find().SetSort(sort).SetLimit(int64(query.Limit)),
+	)
+	if err != nil {
+		return nil, errors.InternalErr(err, "failed to list users")
+	}
+
+	docs := make([]*userDocument, 0, query.Limit)
+	err = cursor.All(ctx, &docs)
+	if err != nil {
+		return nil, errors.InternalErr(err, "failed to read users")
+	}
+
+	list := make([]*domain.User, 0, len(docs))
+	for _, doc := range docs {
+		list = append(list, doc.toDomain())
+	}
+
+	return list, nil
+}
+
+func (um *UserMongoPersistence) readOne(ctx context.Context, filter bson.M) (*domain.User, error) {
+	doc := new(userDocument)
+	err := um.collection.FindOne(ctx, filter).Decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.toDomain(), nil
+}
+
+// nextID atomically increments & returns the users sequence
+func (um *UserMongoPersistence) nextID(ctx context.Context) (int64, error) {
+	counter := struct {
+		Seq int64 `bson:"seq"`
+	}{}
+
+	err := um.counters.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": UserCollection},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, errors.InternalErr(err, "failed to generate user ID")
+	}
+
+	return counter.Seq, nil
+}
+
+// ensureIndexes creates the indexes required by the collection, if they don't exist already
+func (um *UserMongoPersistence) ensureIndexes(ctx context.Context) error {
+	_, err := um.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetName(emailIndex).SetUnique(true).SetPartialFilterExpression(
+				bson.M{"email": bson.M{"$gt": ""}},
+			),
+		},
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("createdAt_id"),
+		},
+	})
+	if err != nil {
+		return errors.InternalErr(err, "failed to create indexes of users collection")
+	}
+
+	_, err = um.collection.Indexes().DropOne(ctx, legacyEmailIndex)
+	cmdErr := mongo.CommandError{}
+	if err != nil && !(errors.As(err, &cmdErr) && cmdErr.Code == mongoIndexNotFound) {
+		return errors.InternalErr(err, "failed to drop the previous email index of users collection")
+	}
+
+	return nil
+}
+
+func NewUserMongoPersistence(ctx context.Context, mongodbCli *datastore.MongoDB) (*UserMongoPersistence, error) {
+	um := &UserMongoPersistence{
 		collection: mongodbCli.Database.Collection(UserCollection),
+		counters:   mongodbCli.Database.Collection(CounterCollection),
 	}
+
+	err := um.ensureIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return um, nil
 }
@@ -3,15 +3,21 @@ package persistence
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"strings"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
 	"github.com/mohamedveron/go_app_template/internal/users/domain"
 )
 
+const (
+	// pgUniqueViolation is the Postgres error code for a violated unique constraint
+	pgUniqueViolation = "23505"
+)
+
 type UserPostgresPersistence struct {
 	qbuilder  squirrel.StatementBuilderType
 	pqdriver  *pgxpool.Pool
@@ -23,65 +29,225 @@ func (us *UserPostgresPersistence) Create(ctx context.Context, u *domain.User) e
 		"firstName": u.FirstName,
 		"lastName":  u.LastName,
 		"mobile":    u.Mobile,
-		"email":     u.Email,
+		"email":     nullableEmail(u.Email),
 		"createdAt": u.CreatedAt,
 		"updatedAt": u.UpdatedAt,
-	}).ToSql()
+		"version":   u.Version,
+	}).Suffix("RETURNING id").ToSql()
 	if err != nil {
-		return errors.New("internal error")
+		return errors.InternalErr(err, "failed to build query")
 	}
 
-	_, err = us.pqdriver.Exec(ctx, query, args...)
+	err = us.pqdriver.QueryRow(ctx, query, args...).Scan(&u.ID)
 	if err != nil {
-		if strings.Contains(err.Error(), "violates unique constraint") {
-			return errors.New("user with email '%s' already exists")
+		pgErr := new(pgconn.PgError)
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return errEmailExists(ctx)
 		}
-		return errors.New("internal error")
+		return errors.InternalErr(err, "failed to create user")
 	}
 
 	return nil
 }
 
+func (us *UserPostgresPersistence) ReadByID(ctx context.Context, id int64) (*domain.User, error) {
+	user, err := us.readOne(ctx, squirrel.Eq{"id": id})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errUserNotFound(err)
+		}
+
+		return nil, errors.InternalErr(err, "failed to read user")
+	}
+
+	return user, nil
+}
+
 func (us *UserPostgresPersistence) ReadByEmail(ctx context.Context, email string) (*domain.User, error) {
+	user, err := us.readOne(ctx, squirrel.Eq{"email": email})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errEmailNotFound(err)
+		}
+
+		return nil, errors.InternalErr(err, "failed to read user")
+	}
+
+	return user, nil
+}
+
+func (us *UserPostgresPersistence) Update(ctx context.Context, u *domain.User) error {
+	query, args, err := us.qbuilder.Update(us.tableName).SetMap(map[string]interface{}{
+		"firstName": u.FirstName,
+		"lastName":  u.LastName,
+		"mobile":    u.Mobile,
+		"email":     nullableEmail(u.Email),
+		"updatedAt": u.UpdatedAt,
+		"version":   squirrel.Expr("version + 1"),
+	}).Where(
+		squirrel.Eq{"id": u.ID, "version": u.Version},
+	).ToSql()
+	if err != nil {
+		return errors.InternalErr(err, "failed to build query")
+	}
+
+	tag, err := us.pqdriver.Exec(ctx, query, args...)
+	if err != nil {
+		pgErr := new(pgconn.PgError)
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return errEmailExists(ctx)
+		}
+		return errors.InternalErr(err, "failed to update user")
+	}
+
+	if tag.RowsAffected() == 0 {
+		// either the user does not exist, or its version has changed
+		_, err = us.ReadByID(ctx, u.ID)
+		if err != nil {
+			return err
+		}
+		return errVersionMismatch(ctx, u.Version)
+	}
+
+	u.Version++
+
+	return nil
+}
+
+func (us *UserPostgresPersistence) Delete(ctx context.Context, id int64) error {
+	query, args, err := us.qbuilder.Delete(us.tableName).Where(
+		squirrel.Eq{"id": id},
+	).ToSql()
+	if err != nil {
+		return errors.InternalErr(err, "failed to build query")
+	}
+
+	tag, err := us.pqdriver.Exec(ctx, query, args...)
+	if err != nil {
+		return errors.InternalErr(err, "failed to delete user")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errUserNotFound(nil)
+	}
+
+	return nil
+}
+
+func (us *UserPostgresPersistence) List(ctx context.Context, query *domain.UsersQuery) ([]*domain.User, error) {
+	where := squirrel.And{}
+	if query.EmailDomain != "" {
+		where = append(where, squirrel.ILike{"email": "%@" + escapeLike(query.EmailDomain)})
+	}
+	if query.NamePrefix != "" {
+		prefix := escapeLike(query.NamePrefix) + "%"
+		where = append(where, squirrel.Or{
+			squirrel.ILike{"firstName": prefix},
+			squirrel.ILike{"lastName": prefix},
+		})
+	}
+	if query.CreatedFrom != nil {
+		where = append(where, squirrel.GtOrEq{"createdAt": *query.CreatedFrom})
+	}
+	if query.CreatedTo != nil {
+		where = append(where, squirrel.Lt{"createdAt": *query.CreatedTo})
+	}
+
+	orderBy := "id"
+	if query.OrderBy == domain.UsersOrderByCreatedAt {
+		orderBy = "createdAt, id"
+		if query.After != nil {
+			where = append(where, squirrel.Expr("(createdAt, id) > (?, ?)", query.After.CreatedAt, query.After.ID))
+		}
+	} else if query.After != nil {
+		where = append(where, squirrel.Gt{"id": query.After.ID})
+	}
+
+	sqlQuery, args, err := us.qbuilder.Select(
+		userColumns...,
+	).From(
+		us.tableName,
+	).Where(
+		where,
+	).OrderBy(
+		orderBy,
+	).Limit(
+		uint64(query.Limit),
+	).ToSql()
+	if err != nil {
+		return nil, errors.InternalErr(err, "failed to build query")
+	}
+
+	rows, err := us.pqdriver.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, errors.InternalErr(err, "failed to list users")
+	}
+	defer rows.Close()
+
+	list := make([]*domain.User, 0, query.Limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, errors.InternalErr(err, "failed to read user")
+		}
+		list = append(list, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.InternalErr(err, "failed to list users")
+	}
+
+	return list, nil
+}
+
+// readOne reads a single user matching the given condition
+func (us *UserPostgresPersistence) readOne(ctx context.Context, where squirrel.Sqlizer) (*domain.User, error) {
 	query, args, err := us.qbuilder.Select(
-		"firstName",
-		"lastName",
-		"mobile",
-		"email",
-		"createdAt",
-		"updatedAt",
+		userColumns...,
 	).From(
 		us.tableName,
 	).Where(
-		squirrel.Eq{
-			"email": email,
-		},
+		where,
 	).ToSql()
 	if err != nil {
-		return nil, errors.New("internal error")
+		return nil, err
 	}
 
+	return scanUser(us.pqdriver.QueryRow(ctx, query, args...))
+}
+
+// userColumns are the columns read by scanUser, in the same order
+var userColumns = []string{
+	"id",
+	"firstName",
+	"lastName",
+	"mobile",
+	"email",
+	"createdAt",
+	"updatedAt",
+	"version",
+}
+
+func scanUser(row pgx.Row) (*domain.User, error) {
 	user := new(domain.User)
 	firstName := new(sql.NullString)
 	lastName := new(sql.NullString)
 	mobile := new(sql.NullString)
 	storeEmail := new(sql.NullString)
 
-	row := us.pqdriver.QueryRow(ctx, query, args...)
-	err = row.Scan(
+	err := row.Scan(
+		&user.ID,
 		firstName,
 		lastName,
 		mobile,
 		storeEmail,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.Version,
 	)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("email not found")
-		}
-
-		return nil, errors.New("internal error")
+		return nil, err
 	}
 
 	user.FirstName = firstName.String
@@ -92,6 +258,19 @@ func (us *UserPostgresPersistence) ReadByEmail(ctx context.Context, email string
 	return user, nil
 }
 
+// nullableEmail returns NULL for an empty email, since the email column is unique & any number of
+// users can be without an email
+func nullableEmail(email string) sql.NullString {
+	return sql.NullString{String: email, Valid: email != ""}
+}
+
+// escapeLike escapes the wildcards of LIKE patterns, so that the value is matched literally
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 func NewUserPostgresPersistence(pqdriver *pgxpool.Pool) (*UserPostgresPersistence, error) {
 	return &UserPostgresPersistence{
 		pqdriver:  pqdriver,
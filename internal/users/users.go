@@ -2,13 +2,20 @@ package users
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
+	"github.com/mohamedveron/go_app_template/internal/pkg/tracing"
 	"github.com/mohamedveron/go_app_template/internal/users/domain"
 )
 
 // CreateUser creates a new user
 func (us *UsersService) CreateUser(ctx context.Context, u *domain.User) (*domain.User, error) {
+	ctx, span := tracing.Start(ctx, "UsersService.CreateUser")
+	defer span.End()
+
 	u.SetDefaults()
 	u.Sanitize()
 
@@ -22,12 +29,34 @@ func (us *UsersService) CreateUser(ctx context.Context, u *domain.User) (*domain
 		return nil, err
 	}
 
+	logger.FromContext(ctx).Infow("user created", "userID", u.ID)
+
+	return u, nil
+}
+
+// ReadByID returns a user which matches the given ID
+func (us *UsersService) ReadByID(ctx context.Context, id int64) (*domain.User, error) {
+	ctx, span := tracing.Start(ctx, "UsersService.ReadByID")
+	defer span.End()
+
+	ctx = logger.WithContext(ctx, "userID", id)
+	u, err := us.persistence.ReadByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
 	return u, nil
 }
 
 // ReadByEmail returns a user which matches the given email
 func (us *UsersService) ReadByEmail(ctx context.Context, email string) (*domain.User, error) {
+	ctx, span := tracing.Start(ctx, "UsersService.ReadByEmail")
+	defer span.End()
+
 	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, errors.Validation("email is required")
+	}
 
 	u, err := us.persistence.ReadByEmail(ctx, email)
 	if err != nil {
@@ -36,3 +65,150 @@ func (us *UsersService) ReadByEmail(ctx context.Context, email string) (*domain.
 
 	return u, nil
 }
+
+// UpdateUser replaces all the fields of an existing user, except its ID & CreatedAt. If u.Version
+// is set, the user is updated only if it's still at the same version
+func (us *UsersService) UpdateUser(ctx context.Context, u *domain.User) (*domain.User, error) {
+	ctx, span := tracing.Start(ctx, "UsersService.UpdateUser")
+	defer span.End()
+
+	ctx = logger.WithContext(ctx, "userID", u.ID)
+	existing, err := us.readVersion(ctx, u.ID, u.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.ApplyPatch(&domain.UserPatch{
+		FirstName: &u.FirstName,
+		LastName:  &u.LastName,
+		Mobile:    &u.Mobile,
+		Email:     &u.Email,
+	})
+
+	return us.update(ctx, existing)
+}
+
+// PatchUser updates only the fields of an existing user, which are set in the patch. If version is
+// not 0, the user is updated only if it's still at the same version
+func (us *UsersService) PatchUser(ctx context.Context, id int64, version int64, patch *domain.UserPatch) (*domain.User, error) {
+	ctx, span := tracing.Start(ctx, "UsersService.PatchUser")
+	defer span.End()
+
+	ctx = logger.WithContext(ctx, "userID", id)
+	existing, err := us.readVersion(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.ApplyPatch(patch)
+
+	return us.update(ctx, existing)
+}
+
+// DeleteUser deletes an existing user
+func (us *UsersService) DeleteUser(ctx context.Context, id int64) error {
+	ctx, span := tracing.Start(ctx, "UsersService.DeleteUser")
+	defer span.End()
+
+	ctx = logger.WithContext(ctx, "userID", id)
+
+	err := us.persistence.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("user deleted")
+
+	return nil
+}
+
+// readVersion reads the user with the given ID, & ensures it's at the given version. Version 0
+// matches any version
+func (us *UsersService) readVersion(ctx context.Context, id int64, version int64) (*domain.User, error) {
+	u, err := us.persistence.ReadByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != 0 && u.Version != version {
+		logger.FromContext(ctx).Infow("user update rejected, version mismatch", "version", version)
+		return nil, errors.PreconditionFailed(
+			fmt.Sprintf("user is at version %d, not %d", u.Version, version),
+		)
+	}
+
+	return u, nil
+}
+
+// update persists the changes of an existing user, u.Version being the version it was read at
+func (us *UsersService) update(ctx context.Context, u *domain.User) (*domain.User, error) {
+	u.Touch()
+	u.Sanitize()
+
+	err := u.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = us.persistence.Update(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Infow("user updated", "version", u.Version)
+
+	return u, nil
+}
+
+// ListUsers returns a page of users matching the query, the cursor is the NextCursor of the previous
+// page & is empty for the first page
+func (us *UsersService) ListUsers(ctx context.Context, query *domain.UsersQuery, cursor string) (*domain.UsersPage, error) {
+	ctx, span := tracing.Start(ctx, "UsersService.ListUsers")
+	defer span.End()
+
+	q := *query
+	if q.OrderBy == "" {
+		q.OrderBy = domain.UsersOrderByID
+	}
+	if q.OrderBy != domain.UsersOrderByID && q.OrderBy != domain.UsersOrderByCreatedAt {
+		return nil, errors.Validation(fmt.Sprintf("invalid orderBy '%s'", q.OrderBy))
+	}
+
+	switch {
+	case q.Limit == 0:
+		q.Limit = DefaultListLimit
+	case q.Limit < 0:
+		return nil, errors.Validation("limit should be greater than 0")
+	case q.Limit > MaxListLimit:
+		q.Limit = MaxListLimit
+	}
+
+	if cursor != "" {
+		after, err := decodeCursor(cursor, q.OrderBy)
+		if err != nil {
+			return nil, err
+		}
+		q.After = after
+	}
+
+	// an extra user is read to know if there's a next page
+	limit := q.Limit
+	q.Limit++
+	list, err := us.persistence.List(ctx, &q)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.UsersPage{
+		Users: list,
+	}
+	if len(list) > limit {
+		page.Users = list[:limit]
+		page.NextCursor, err = encodeCursor(page.Users[limit-1].Cursor(), q.OrderBy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
@@ -1,12 +1,19 @@
 package users
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
 	"github.com/mohamedveron/go_app_template/internal/users/domain"
+	"github.com/mohamedveron/go_app_template/internal/users/persistence"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestUser_Sanitize(t *testing.T) {
@@ -101,6 +108,21 @@ func TestUser_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "without email & mobile",
+			fields: fields{
+				FirstName: "Jane",
+			},
+			wantErr: false,
+		},
+		{
+			name: "without name",
+			fields: fields{
+				Mobile: "9876543210",
+				Email:  "jane.doe@example.com",
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -118,3 +140,244 @@ func TestUser_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestUsersService_CreateUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     *domain.User
+		wantKind errors.Kind
+		wantErr  bool
+	}{
+		{
+			name: "valid user",
+			user: &domain.User{
+				FirstName: " Jane ",
+				LastName:  "Doe",
+				Email:     " jane.doe@example.com ",
+			},
+		},
+		{
+			name: "invalid email",
+			user: &domain.User{
+				FirstName: "Jane",
+				Email:     "jane.doeexample.com",
+			},
+			wantKind: errors.KindValidation,
+			wantErr:  true,
+		},
+		{
+			name: "duplicate email",
+			user: &domain.User{
+				FirstName: "John",
+				Email:     "existing@example.com",
+			},
+			wantKind: errors.KindConflict,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := persistence.NewUserMemoryPersistence()
+			err := store.Create(ctx, &domain.User{Email: "existing@example.com"})
+			if err != nil {
+				t.Fatalf("failed to create existing user: %v", err)
+			}
+
+			us, _ := NewService(store)
+			u, err := us.CreateUser(ctx, tt.user)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if errors.KindOf(err) != tt.wantKind {
+					t.Errorf("CreateUser() error kind = %v, want %v", errors.KindOf(err), tt.wantKind)
+				}
+				return
+			}
+
+			got, err := us.ReadByID(ctx, u.ID)
+			if err != nil {
+				t.Fatalf("ReadByID() error = %v", err)
+			}
+			if got.FirstName != "Jane" || got.Email != "jane.doe@example.com" {
+				t.Errorf("expected sanitized user, got %+v", got)
+			}
+			if got.CreatedAt == nil || got.UpdatedAt == nil {
+				t.Errorf("expected defaults to be set, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestUsersService_ListUsers(t *testing.T) {
+	ctx := context.Background()
+	us, _ := NewService(persistence.NewUserMemoryPersistence())
+
+	const total = 5
+	for i := 0; i < total; i++ {
+		_, err := us.CreateUser(ctx, &domain.User{FirstName: "Jane", Email: fmt.Sprintf("user%d@example.com", i)})
+		if err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+	}
+
+	for _, orderBy := range []domain.UsersOrderBy{domain.UsersOrderByID, domain.UsersOrderByCreatedAt} {
+		t.Run(string(orderBy), func(t *testing.T) {
+			seen := 0
+			cursor := ""
+			for pages := 1; ; pages++ {
+				page, err := us.ListUsers(ctx, &domain.UsersQuery{OrderBy: orderBy, Limit: 2}, cursor)
+				if err != nil {
+					t.Fatalf("ListUsers() error = %v", err)
+				}
+				seen += len(page.Users)
+
+				if page.NextCursor == "" {
+					if pages != 3 {
+						t.Errorf("got %d pages, want 3", pages)
+					}
+					break
+				}
+				cursor = page.NextCursor
+			}
+
+			if seen != total {
+				t.Errorf("listed %d users, want %d", seen, total)
+			}
+		})
+	}
+
+	t.Run("cursor of a different orderBy", func(t *testing.T) {
+		page, err := us.ListUsers(ctx, &domain.UsersQuery{OrderBy: domain.UsersOrderByID, Limit: 1}, "")
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+
+		_, err = us.ListUsers(ctx, &domain.UsersQuery{OrderBy: domain.UsersOrderByCreatedAt}, page.NextCursor)
+		if errors.KindOf(err) != errors.KindValidation {
+			t.Errorf("ListUsers() error = %v, want validation error", err)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, err := us.ListUsers(ctx, &domain.UsersQuery{}, "not-a-cursor")
+		if errors.KindOf(err) != errors.KindValidation {
+			t.Errorf("ListUsers() error = %v, want validation error", err)
+		}
+	})
+
+	t.Run("limit is capped", func(t *testing.T) {
+		store := persistence.NewUserMemoryPersistence()
+		for i := 0; i < MaxListLimit+1; i++ {
+			err := store.Create(ctx, &domain.User{Email: fmt.Sprintf("user%d@example.com", i)})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+		}
+
+		capped, _ := NewService(store)
+		page, err := capped.ListUsers(ctx, &domain.UsersQuery{Limit: MaxListLimit * 2}, "")
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+		if len(page.Users) != MaxListLimit || page.NextCursor == "" {
+			t.Errorf("got %d users & next cursor '%s', want %d users & a next cursor",
+				len(page.Users), page.NextCursor, MaxListLimit)
+		}
+	})
+}
+
+func TestUsersService_PatchUser(t *testing.T) {
+	ctx := context.Background()
+	us, _ := NewService(persistence.NewUserMemoryPersistence())
+
+	u, err := us.CreateUser(ctx, &domain.User{FirstName: "Jane", Email: "jane.doe@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	createdVersion := u.Version
+
+	firstName := " John "
+	patched, err := us.PatchUser(ctx, u.ID, createdVersion, &domain.UserPatch{FirstName: &firstName})
+	if err != nil {
+		t.Fatalf("PatchUser() error = %v", err)
+	}
+	if patched.FirstName != "John" || patched.Email != u.Email {
+		t.Errorf("expected only the sanitized first name to change, got %+v", patched)
+	}
+	if patched.Version != createdVersion+1 {
+		t.Errorf("Version = %d, want %d", patched.Version, createdVersion+1)
+	}
+	if !patched.UpdatedAt.After(*u.CreatedAt) {
+		t.Errorf("expected UpdatedAt to be bumped, got %v", patched.UpdatedAt)
+	}
+
+	_, err = us.PatchUser(ctx, u.ID, createdVersion, &domain.UserPatch{FirstName: &firstName})
+	if errors.KindOf(err) != errors.KindPreconditionFailed {
+		t.Errorf("PatchUser() with a stale version error = %v, want precondition failed", err)
+	}
+
+	// version 0 updates irrespective of the current version
+	_, err = us.PatchUser(ctx, u.ID, 0, &domain.UserPatch{FirstName: &firstName})
+	if err != nil {
+		t.Errorf("PatchUser() without a version error = %v", err)
+	}
+}
+
+func TestUsersService_logContext(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	defer logger.Replace(zap.New(core))()
+
+	ctx := logger.WithContext(context.Background(), "requestID", "abc")
+	us, _ := NewService(persistence.NewUserMemoryPersistence())
+
+	u, err := us.CreateUser(ctx, &domain.User{FirstName: "Jane", Email: "jane.doe@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	_, err = us.UpdateUser(ctx, &domain.User{ID: u.ID, FirstName: "John", Email: u.Email})
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	// the version check of the persistence rejects the update, as the user was updated concurrently
+	err = us.persistence.Update(logger.WithContext(ctx, "userID", u.ID), u)
+	if errors.KindOf(err) != errors.KindPreconditionFailed {
+		t.Fatalf("Update() with a stale version error = %v, want precondition failed", err)
+	}
+
+	entries := logs.All()
+	if len(entries) != 3 {
+		t.Fatalf("got %d log entries, want 3", len(entries))
+	}
+	for _, entry := range entries {
+		fields := entry.ContextMap()
+		if fields["requestID"] != "abc" {
+			t.Errorf("'%s' requestID = %v, want abc", entry.Message, fields["requestID"])
+		}
+		if fields["userID"] != u.ID {
+			t.Errorf("'%s' userID = %v, want %d", entry.Message, fields["userID"], u.ID)
+		}
+	}
+}
+
+func TestUser_MarshalLogObject(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	defer logger.Replace(zap.New(core))()
+
+	u := &domain.User{ID: 7, FirstName: "Jane", LastName: "Doe", Mobile: "+31600000000", Email: "jane.doe@example.com", Version: 2}
+	logger.Infow("user", "user", u)
+	logger.Info(u)
+
+	for _, entry := range logs.All() {
+		logged := fmt.Sprintf("%s %v", entry.Message, entry.ContextMap())
+		for _, pii := range []string{u.FirstName, u.LastName, u.Mobile, u.Email} {
+			if strings.Contains(logged, pii) {
+				t.Errorf("'%s' should not be logged, got %s", pii, logged)
+			}
+		}
+		if !strings.Contains(logged, "7") {
+			t.Errorf("expected the user ID to be logged, got %s", logged)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/circuitbreaker"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/sashabaranov/go-openai"
+)
+
+// The errors of the LLM providers, which can be checked with errors.Is. They're wrapped in typed
+// errors, so that the HTTP layer responds with 429, 502, 503 or 504
+var (
+	// ErrRateLimited is returned when the provider rejects the request for exceeding its rate limits
+	ErrRateLimited = stderrors.New("rate limited by the LLM provider")
+	// ErrAuthentication is returned when the provider rejects the credentials, e.g. a revoked key
+	ErrAuthentication = stderrors.New("LLM provider authentication failed")
+	// ErrTimeout is returned when the provider doesn't respond within the timeout
+	ErrTimeout = stderrors.New("LLM provider timed out")
+	// ErrContentFiltered is returned when the prompt or the completion is blocked by the content
+	// filter of the provider
+	ErrContentFiltered = stderrors.New("blocked by the LLM provider content filter")
+	// ErrUpstream is returned for any other failure of the provider, e.g. a 5xx response
+	ErrUpstream = stderrors.New("LLM provider failed")
+	// ErrCircuitOpen is returned without calling the provider, while its circuit breaker is open
+	ErrCircuitOpen = circuitbreaker.ErrOpen
+)
+
+// errStreamStalled is the cause of cancelling a stream when no chunk is received within the timeout
+var errStreamStalled = stderrors.New("no chunk received within the timeout")
+
+// providerErr returns the typed error of a failed call to the provider. ctx is the context of the
+// call, to tell apart the timeouts
+func providerErr(ctx context.Context, err error) error {
+	if stderrors.Is(err, ErrCircuitOpen) {
+		return errors.UnavailableErr(err, "the LLM provider is failing, retry later")
+	}
+
+	netErr := net.Error(nil)
+	if stderrors.Is(err, context.DeadlineExceeded) ||
+		stderrors.Is(context.Cause(ctx), errStreamStalled) ||
+		(stderrors.As(err, &netErr) && netErr.Timeout()) {
+		return typedErr(ErrTimeout, err)
+	}
+
+	// the caller gave up, e.g. the client disconnected, so it's not a failure of the provider
+	if stderrors.Is(err, context.Canceled) {
+		return errors.InternalErr(err, "LLM call cancelled")
+	}
+
+	apiErr := &openai.APIError{}
+	if stderrors.As(err, &apiErr) {
+		code, _ := apiErr.Code.(string)
+		return typedErr(classify(apiErr.HTTPStatusCode, code), err)
+	}
+
+	reqErr := &openai.RequestError{}
+	if stderrors.As(err, &reqErr) {
+		return typedErr(classify(reqErr.HTTPStatusCode, ""), err)
+	}
+
+	return typedErr(ErrUpstream, err)
+}
+
+// classify returns the error of the provider for the HTTP status & the error code it responded with
+func classify(status int, code string) error {
+	switch {
+	case code == "content_filter" || code == "content_policy_violation":
+		return ErrContentFiltered
+	// an exhausted quota is not resolved by retrying, unlike the rate limits
+	case status == http.StatusTooManyRequests && code != "insufficient_quota":
+		return ErrRateLimited
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrAuthentication
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return ErrTimeout
+	default:
+		return ErrUpstream
+	}
+}
+
+// typedErr wraps err, if any, in the typed error of kind, which has the public message of kind
+func typedErr(kind error, err error) error {
+	cause := kind
+	if err != nil {
+		cause = fmt.Errorf("%w: %w", kind, err)
+	}
+
+	switch kind {
+	case ErrRateLimited:
+		return errors.RateLimitedErr(cause, "the LLM provider is rate limiting the requests, retry later")
+	case ErrTimeout:
+		return errors.GatewayTimeoutErr(cause, "the LLM provider did not respond in time")
+	case ErrContentFiltered:
+		return errors.BadGatewayErr(cause, "the content was blocked by the LLM provider content filter")
+	case ErrAuthentication:
+		return errors.BadGatewayErr(cause, "the LLM provider rejected the credentials")
+	default:
+		return errors.BadGatewayErr(cause, "the LLM provider failed")
+	}
+}
@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+)
+
+// defaultFakeResponses are the responses of the fixtures file shipped along with the binary
+//
+//go:embed fixtures/llm.json
+var defaultFakeResponses []byte
+
+// FakeResponse is the completion scripted for a prompt
+type FakeResponse struct {
+	// Prompt is the input of the prompts the response is for, e.g. the topic. The response
+	// without a prompt is used for all the prompts which have no response of their own
+	Prompt string `json:"prompt"`
+	// Deltas are the parts of the completion as they're streamed, the completion is all of them
+	// joined
+	Deltas []string `json:"deltas"`
+	// FinishReason is stop by default
+	FinishReason string `json:"finishReason"`
+	// Error fails the completion, once all the deltas are streamed
+	Error string `json:"error"`
+	// Status is the HTTP status of the failure, e.g. 429, which decides its typed error like for the
+	// responses of the providers. Failures without a status are ErrUpstream
+	Status int `json:"status"`
+}
+
+// Fake is a deterministic LLMClient, which responds with the completions scripted for the inputs
+// of the prompts. It's meant for tests & local development, without calling any provider
+type Fake struct {
+	responses map[string]FakeResponse
+}
+
+// Complete returns the scripted completion of the prompt
+func (f *Fake) Complete(ctx context.Context, prompt *Prompt) (string, error) {
+	resp, err := f.response(prompt.Input)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.Error != "" {
+		return "", resp.err()
+	}
+
+	return strings.Join(resp.Deltas, ""), nil
+}
+
+// Stream streams the scripted completion of the prompt, a delta at a time
+func (f *Fake) Stream(ctx context.Context, prompt *Prompt, onDelta func(delta string) error) (*Completion, error) {
+	resp, err := f.response(prompt.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, delta := range resp.Deltas {
+		if ctx.Err() != nil {
+			return nil, errors.InternalErr(ctx.Err(), "completion stopped")
+		}
+
+		err = onDelta(delta)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.Error != "" {
+		return nil, resp.err()
+	}
+
+	finishReason := resp.FinishReason
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+
+	// the tokens are approximated by words, which is enough to be deterministic
+	usage := Usage{
+		PromptTokens:     len(strings.Fields(prompt.System)) + len(strings.Fields(prompt.User)),
+		CompletionTokens: len(resp.Deltas),
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	return &Completion{FinishReason: finishReason, Usage: usage}, nil
+}
+
+// Fingerprint identifies the fake, whose completions differ from any provider
+func (f *Fake) Fingerprint() string {
+	return "fake"
+}
+
+func (r *FakeResponse) err() error {
+	return typedErr(classify(r.Status, ""), errors.Internal(r.Error))
+}
+
+func (f *Fake) response(input string) (*FakeResponse, error) {
+	resp, ok := f.responses[input]
+	if ok {
+		return &resp, nil
+	}
+
+	resp, ok = f.responses[""]
+	if ok {
+		return &resp, nil
+	}
+
+	return nil, errors.NotFound(fmt.Sprintf("no fake response for the prompt '%s'", input))
+}
+
+// NewFake returns a Fake responding with the responses given
+func NewFake(responses ...FakeResponse) *Fake {
+	f := &Fake{responses: make(map[string]FakeResponse, len(responses))}
+	for _, resp := range responses {
+		f.responses[resp.Prompt] = resp
+	}
+
+	return f
+}
+
+// NewFakeFromFile returns a Fake responding with the responses in the JSON fixtures file, which
+// is a list of FakeResponse
+func NewFakeFromFile(path string) (*Fake, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.InternalErr(err, fmt.Sprintf("failed to read the fake responses '%s'", path))
+	}
+
+	return newFakeFromJSON(raw, path)
+}
+
+// NewDefaultFake returns a Fake responding with the responses of proxy/fixtures/llm.json, which is
+// shipped along with the binary
+func NewDefaultFake() (*Fake, error) {
+	return newFakeFromJSON(defaultFakeResponses, "fixtures/llm.json")
+}
+
+func newFakeFromJSON(raw []byte, name string) (*Fake, error) {
+	responses := []FakeResponse{}
+	err := json.Unmarshal(raw, &responses)
+	if err != nil {
+		return nil, errors.ValidationErr(err, fmt.Sprintf("invalid fake responses '%s'", name))
+	}
+
+	return NewFake(responses...), nil
+}
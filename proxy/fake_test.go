@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+)
+
+func TestNewFakeFromFile(t *testing.T) {
+	fake, err := NewFakeFromFile("fixtures/llm.json")
+	if err != nil {
+		t.Fatalf("NewFakeFromFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		prompt           string
+		wantCompletion   string
+		wantFinishReason string
+		wantErr          bool
+	}{
+		{
+			name:             "scripted",
+			prompt:           "go",
+			wantCompletion:   "Go is an open source programming language that makes it simple to build secure, scalable systems.",
+			wantFinishReason: "stop",
+		},
+		{
+			name:             "truncated",
+			prompt:           "truncated",
+			wantCompletion:   "This paragraph stops before it's",
+			wantFinishReason: "length",
+		},
+		{
+			name:           "failure",
+			prompt:         "failure",
+			wantCompletion: "This paragraph fails ",
+			wantErr:        true,
+		},
+		{
+			name:             "not scripted",
+			prompt:           "rust",
+			wantCompletion:   "This is a fake paragraph, scripted in proxy/fixtures/llm.json.",
+			wantFinishReason: "stop",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := strings.Builder{}
+			completion, err := fake.Stream(context.Background(), &Prompt{Input: tt.prompt}, func(delta string) error {
+				b.WriteString(delta)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Stream() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if b.String() != tt.wantCompletion {
+				t.Errorf("Stream() streamed %q, want %q", b.String(), tt.wantCompletion)
+			}
+			if err == nil && completion.FinishReason != tt.wantFinishReason {
+				t.Errorf("Stream() finish reason = %s, want %s", completion.FinishReason, tt.wantFinishReason)
+			}
+
+			got, err := fake.Complete(context.Background(), &Prompt{Input: tt.prompt})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Complete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.wantCompletion {
+				t.Errorf("Complete() = %q, want %q", got, tt.wantCompletion)
+			}
+		})
+	}
+}
+
+func TestNewDefaultFake(t *testing.T) {
+	fake, err := NewDefaultFake()
+	if err != nil {
+		t.Fatalf("NewDefaultFake() error = %v", err)
+	}
+
+	fromFile, err := NewFakeFromFile("fixtures/llm.json")
+	if err != nil {
+		t.Fatalf("NewFakeFromFile() error = %v", err)
+	}
+
+	if len(fake.responses) == 0 || !reflect.DeepEqual(fake.responses, fromFile.responses) {
+		t.Errorf("NewDefaultFake() responses = %+v, want the responses of fixtures/llm.json", fake.responses)
+	}
+}
+
+func TestFake_notScripted(t *testing.T) {
+	_, err := NewFake().Complete(context.Background(), &Prompt{Input: "go"})
+	if errors.KindOf(err) != errors.KindNotFound {
+		t.Errorf("Complete() error = %v, want not found", err)
+	}
+}
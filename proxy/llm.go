@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"context"
+)
+
+// LLMClient generates completions of prompts with a large language model. It's implemented by
+// the adapter of every provider, e.g. OpenAI, & by Fake for tests & local development
+type LLMClient interface {
+	// Complete returns the completion of the prompt, once it's fully generated
+	Complete(ctx context.Context, prompt *Prompt) (string, error)
+	// Stream generates the completion of the prompt, onDelta is called with every part of the
+	// completion as soon as it's generated. The generation is stopped once ctx is done, or if
+	// onDelta returns an error
+	Stream(ctx context.Context, prompt *Prompt, onDelta func(delta string) error) (*Completion, error)
+	// Fingerprint identifies the provider & its default model, since the completions of a prompt
+	// differ by them. It's part of the keys of the cached completions
+	Fingerprint() string
+}
+
+// Prompt is the input of a completion, it's usually rendered from a PromptTemplate
+type Prompt struct {
+	// Template & Version identify the template the prompt was rendered from, if any
+	Template string
+	Version  int
+	// Input is the untrusted input the user message was rendered with, e.g. the topic. It's not
+	// sent to the provider on its own, but it's what the responses of Fake are scripted by, so
+	// that they don't depend on the wording of the templates
+	Input string
+	// System is the system message, the instructions of the model. It's not sent when empty
+	System string
+	// User is the user message
+	User string
+	// Model overrides the model of the client, when it's not empty
+	Model string
+	// Temperature is the sampling temperature, between 0 & 2. The default of the provider is
+	// used when it's 0
+	Temperature float32
+	// MaxTokens is the limit of the tokens of the completion, there's no limit when it's 0
+	MaxTokens int
+}
+
+// Usage is the number of tokens used by a completion
+type Usage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	TotalTokens      int `json:"totalTokens"`
+}
+
+// Completion is the outcome of a streamed completion, once the stream ends
+type Completion struct {
+	// FinishReason is why the generation stopped, e.g. stop, or length if the token limit was reached
+	FinishReason string `json:"finishReason"`
+	Usage        Usage  `json:"usage"`
+}
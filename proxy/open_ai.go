@@ -2,39 +2,306 @@ package proxy
 
 import (
 	"context"
-	"fmt"
+	"io"
+	"net/http"
+	"time"
 
+	"github.com/mohamedveron/go_app_template/internal/pkg/circuitbreaker"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/mohamedveron/go_app_template/internal/pkg/health"
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
+	"github.com/mohamedveron/go_app_template/internal/pkg/metrics"
+	"github.com/mohamedveron/go_app_template/internal/pkg/tracing"
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// OpenAIConfig is the configuration of the OpenAI API client
+type OpenAIConfig struct {
+	APIKey string
+	// Model used for the completions, e.g. gpt-4
+	Model string
+	// BaseURL overrides the URL of the OpenAI API, e.g. for an OpenAI compatible provider or a
+	// proxy. The URL of the OpenAI API is used when it's empty
+	BaseURL string
+	// Timeout of every call, streams time out when no chunk is received within it. It includes the
+	// retries
+	Timeout time.Duration
+	// Retry is the configuration of the retries of the requests failed with a transient error
+	Retry RetryConfig
+	// Breaker is the configuration of the circuit breaker of the requests
+	Breaker circuitbreaker.Config
+}
+
+// OpenAI is the LLMClient of the OpenAI chat completions API
 type OpenAI struct {
-	token string
-}
-
-func (ai *OpenAI) GetMessage(token string) string {
-	client := openai.NewClient(ai.token)
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT4,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: token,
-				},
-			},
-		},
+	client  *openai.Client
+	breaker *circuitbreaker.Breaker
+	model   string
+	timeout time.Duration
+}
+
+// Complete returns the chat completion of the prompt. The completion is traced as a child span of
+// the span in ctx. Failures of the API are returned as the typed errors of the proxy, e.g.
+// ErrRateLimited
+func (ai *OpenAI) Complete(ctx context.Context, prompt *Prompt) (string, error) {
+	req := ai.request(prompt)
+	ctx, span := tracing.Start(
+		ctx,
+		"openai chat_completion",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(spanAttributes(req, prompt)...),
+	)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, ai.timeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := ai.client.CreateChatCompletion(ctx, req)
+	content := ""
+	if err != nil {
+		err = providerErr(ctx, err)
+	} else {
+		content, err = choiceContent(resp)
+	}
+	metrics.ObserveOpenAICall("chat_completion", time.Since(start), err, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	tracing.RecordError(span, err)
+	span.SetAttributes(
+		attribute.Int("openai.usage.prompt_tokens", resp.Usage.PromptTokens),
+		attribute.Int("openai.usage.completion_tokens", resp.Usage.CompletionTokens),
 	)
+	if err != nil {
+		return "", err
+	}
+
+	return content, nil
+}
+
+// choiceContent returns the content of the first choice of the chat completion
+func choiceContent(resp openai.ChatCompletionResponse) (string, error) {
+	if len(resp.Choices) == 0 {
+		return "", typedErr(ErrUpstream, errors.Internal("the chat completion has no choices"))
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason == openai.FinishReasonContentFilter {
+		return "", typedErr(ErrContentFiltered, nil)
+	}
+
+	return choice.Message.Content, nil
+}
+
+// Stream streams the chat completion of the prompt, onDelta is called with every part of the
+// content as soon as it's received. The upstream request is cancelled once ctx is done, or if
+// onDelta returns an error, or when no chunk is received within the timeout. The completion is
+// traced as a child span of the span in ctx
+func (ai *OpenAI) Stream(
+	ctx context.Context,
+	prompt *Prompt,
+	onDelta func(delta string) error,
+) (*Completion, error) {
+	req := ai.request(prompt)
+	ctx, span := tracing.Start(
+		ctx,
+		"openai chat_completion_stream",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(spanAttributes(req, prompt)...),
+	)
+	defer span.End()
+
+	completion := &Completion{}
+	start := time.Now()
+	err := ai.stream(ctx, req, completion, onDelta)
+
+	usage := completion.Usage
+	metrics.ObserveOpenAICall("chat_completion_stream", time.Since(start), err, usage.PromptTokens, usage.CompletionTokens)
+	tracing.RecordError(span, err)
+	span.SetAttributes(
+		attribute.String("openai.finish_reason", completion.FinishReason),
+		attribute.Int("openai.usage.prompt_tokens", usage.PromptTokens),
+		attribute.Int("openai.usage.completion_tokens", usage.CompletionTokens),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return completion, nil
+}
+
+func (ai *OpenAI) stream(
+	ctx context.Context,
+	req openai.ChatCompletionRequest,
+	completion *Completion,
+	onDelta func(delta string) error,
+) error {
+	req.Stream = true
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stalled := time.AfterFunc(ai.timeout, func() { cancel(errStreamStalled) })
+	defer stalled.Stop()
+
+	stream, err := ai.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return providerErr(ctx, err)
+	}
+	defer stream.Close()
+
+	for {
+		stalled.Reset(ai.timeout)
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return providerErr(ctx, err)
+		}
 
+		// usage is only in the last chunk, which has no choices
+		if resp.Usage != nil {
+			completion.Usage = Usage{
+				PromptTokens:     resp.Usage.PromptTokens,
+				CompletionTokens: resp.Usage.CompletionTokens,
+				TotalTokens:      resp.Usage.TotalTokens,
+			}
+		}
+		if len(resp.Choices) == 0 {
+			continue
+		}
+
+		choice := resp.Choices[0]
+		if choice.FinishReason != "" {
+			completion.FinishReason = string(choice.FinishReason)
+		}
+		if choice.Delta.Content == "" {
+			continue
+		}
+
+		err = onDelta(choice.Delta.Content)
+		if err != nil {
+			return err
+		}
+	}
+
+	if completion.FinishReason == string(openai.FinishReasonContentFilter) {
+		return typedErr(ErrContentFiltered, nil)
+	}
+
+	return nil
+}
+
+// Fingerprint identifies the default model of the completions, the prompts overriding it differ
+// by their model
+func (ai *OpenAI) Fingerprint() string {
+	return "openai/" + ai.model
+}
+
+// request returns the chat completion request of the prompt, with the model of the client unless
+// the prompt overrides it
+func (ai *OpenAI) request(prompt *Prompt) openai.ChatCompletionRequest {
+	req := openai.ChatCompletionRequest{
+		Model:       ai.model,
+		Temperature: prompt.Temperature,
+		MaxTokens:   prompt.MaxTokens,
+	}
+	if prompt.Model != "" {
+		req.Model = prompt.Model
+	}
+
+	if prompt.System != "" {
+		req.Messages = append(req.Messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: prompt.System,
+		})
+	}
+	req.Messages = append(req.Messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: prompt.User,
+	})
+
+	return req
+}
+
+// spanAttributes returns the attributes of the span of the request, the messages are left out
+// since they include the input of the users
+func spanAttributes(req openai.ChatCompletionRequest, prompt *Prompt) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.String("openai.model", req.Model)}
+	if prompt.Template != "" {
+		attrs = append(attrs,
+			attribute.String("prompt.template", prompt.Template),
+			attribute.Int("prompt.version", prompt.Version),
+		)
+	}
+
+	return attrs
+}
+
+// Ping checks if the OpenAI API is reachable with the token, by listing the models
+func (ai *OpenAI) Ping(ctx context.Context) error {
+	_, err := ai.client.ListModels(ctx)
 	if err != nil {
-		fmt.Printf("ChatCompletion error: %v\n", err)
-		return ""
+		return errors.InternalErr(err, "failed to list the OpenAI models")
+	}
+
+	return nil
+}
+
+// RegisterHealthCheck registers the reachability of the OpenAI API as a non-critical health check,
+// only the APIs using OpenAI are affected when it's unreachable
+func (ai *OpenAI) RegisterHealthCheck() {
+	health.Register(health.Check{
+		Name:     "openai",
+		Critical: false,
+		Check:    ai.Ping,
+	})
+}
+
+// checkCircuit fails while the circuit breaker is open, i.e. while the calls fail fast
+func (ai *OpenAI) checkCircuit(ctx context.Context) error {
+	if ai.breaker.State() == circuitbreaker.StateOpen {
+		return errors.Internal("the circuit breaker of the OpenAI API is open")
 	}
 
-	str := fmt.Sprintf("%s", resp.Choices[0].Message.Content)
-	return str
+	return nil
 }
 
-func NewOpenAI(token string) OpenAI {
-	return OpenAI{token: token}
+// NewOpenAI returns the LLMClient of the OpenAI API. The state of its circuit breaker is registered
+// as a non-critical health check
+func NewOpenAI(cfg *OpenAIConfig) *OpenAI {
+	bcfg := cfg.Breaker
+	bcfg.OnStateChange = func(state string) {
+		metrics.SetOpenAICircuitState(state)
+		logger.Warn("circuit breaker of the OpenAI API is ", state)
+	}
+	breaker := circuitbreaker.New(bcfg)
+	metrics.SetOpenAICircuitState(circuitbreaker.StateClosed)
+
+	ocfg := openai.DefaultConfig(cfg.APIKey)
+	if cfg.BaseURL != "" {
+		ocfg.BaseURL = cfg.BaseURL
+	}
+	ocfg.HTTPClient = &http.Client{
+		Transport: &retryTransport{
+			next:    http.DefaultTransport,
+			cfg:     cfg.Retry,
+			breaker: breaker,
+		},
+	}
+
+	ai := &OpenAI{
+		client:  openai.NewClientWithConfig(ocfg),
+		breaker: breaker,
+		model:   cfg.Model,
+		timeout: cfg.Timeout,
+	}
+	health.Register(health.Check{
+		Name:     "openai_circuit",
+		Critical: false,
+		Check:    ai.checkCircuit,
+	})
+
+	return ai
 }
@@ -0,0 +1,257 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"github.com/sashabaranov/go-openai"
+)
+
+// newTestOpenAI returns the OpenAI client of a fake OpenAI API, served by handler. The retries &
+// the circuit breaker are disabled, unless configured
+func newTestOpenAI(t *testing.T, handler http.HandlerFunc, configure ...func(cfg *OpenAIConfig)) *OpenAI {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	cfg := &OpenAIConfig{
+		APIKey:  "token",
+		Model:   "gpt-4",
+		BaseURL: srv.URL,
+		Timeout: 200 * time.Millisecond,
+	}
+	for _, c := range configure {
+		c(cfg)
+	}
+
+	return NewOpenAI(cfg)
+}
+
+// streamHandler fakes the OpenAI chat completions API, streaming the chunks as Server-Sent Events
+func streamHandler(chunks ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}
+}
+
+// errorHandler fakes a failure of the OpenAI API
+func errorHandler(status int, code string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"error":{"message":"failed","type":"error","code":%q}}`, code)
+	}
+}
+
+func TestOpenAI_Stream(t *testing.T) {
+	ai := newTestOpenAI(t, streamHandler(
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"Hello"}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":" world"}}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+	))
+
+	deltas := []string{}
+	got, err := ai.Stream(context.Background(), &Prompt{User: "greet"}, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	if strings.Join(deltas, "|") != "Hello| world" {
+		t.Errorf("Stream() deltas = %q, want [Hello, world]", deltas)
+	}
+	want := Completion{
+		FinishReason: "stop",
+		Usage:        Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5},
+	}
+	if *got != want {
+		t.Errorf("Stream() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestOpenAI_Stream_stopped(t *testing.T) {
+	ai := newTestOpenAI(t, streamHandler(
+		`{"choices":[{"index":0,"delta":{"content":"Hello"}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":" world"}}]}`,
+	))
+
+	// e.g. the client disconnected, & the delta could not be written
+	calls := 0
+	_, err := ai.Stream(context.Background(), &Prompt{User: "greet"}, func(delta string) error {
+		calls++
+		return errors.Internal("client disconnected")
+	})
+	if err == nil {
+		t.Fatal("Stream() expected an error")
+	}
+	if calls != 1 {
+		t.Errorf("Stream() onDelta calls = %d, want 1", calls)
+	}
+}
+
+func TestOpenAI_errors(t *testing.T) {
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantErr    error
+		wantStatus int
+	}{
+		{
+			name:       "rate limited",
+			handler:    errorHandler(http.StatusTooManyRequests, "rate_limit_exceeded"),
+			wantErr:    ErrRateLimited,
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:       "quota exhausted",
+			handler:    errorHandler(http.StatusTooManyRequests, "insufficient_quota"),
+			wantErr:    ErrUpstream,
+			wantStatus: http.StatusBadGateway,
+		},
+		{
+			name:       "invalid API key",
+			handler:    errorHandler(http.StatusUnauthorized, "invalid_api_key"),
+			wantErr:    ErrAuthentication,
+			wantStatus: http.StatusBadGateway,
+		},
+		{
+			name:       "content filter",
+			handler:    errorHandler(http.StatusBadRequest, "content_filter"),
+			wantErr:    ErrContentFiltered,
+			wantStatus: http.StatusBadGateway,
+		},
+		{
+			name:       "upstream failure",
+			handler:    errorHandler(http.StatusServiceUnavailable, ""),
+			wantErr:    ErrUpstream,
+			wantStatus: http.StatusBadGateway,
+		},
+		{
+			name:       "timeout",
+			handler:    slow,
+			wantErr:    ErrTimeout,
+			wantStatus: http.StatusGatewayTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ai := newTestOpenAI(t, tt.handler)
+
+			_, err := ai.Complete(context.Background(), &Prompt{User: "greet"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Complete() error = %v, want %v", err, tt.wantErr)
+			}
+			if status, _, _ := errors.HTTPStatusCodeMessage(err); status != tt.wantStatus {
+				t.Errorf("Complete() error status = %d, want %d", status, tt.wantStatus)
+			}
+
+			_, err = ai.Stream(context.Background(), &Prompt{User: "greet"}, func(string) error { return nil })
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Stream() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenAI_Complete_prompt(t *testing.T) {
+	var got openai.ChatCompletionRequest
+	ai := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}]}`)
+	})
+
+	_, err := ai.Complete(context.Background(), &Prompt{
+		System:      "Be brief",
+		User:        "greet",
+		Model:       "gpt-4o",
+		Temperature: 0.5,
+		MaxTokens:   100,
+	})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	want := openai.ChatCompletionRequest{
+		Model: "gpt-4o",
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "Be brief"},
+			{Role: openai.ChatMessageRoleUser, Content: "greet"},
+		},
+		Temperature: 0.5,
+		MaxTokens:   100,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("request = %+v, want %+v", got, want)
+	}
+}
+
+func TestOpenAI_Complete_contentFiltered(t *testing.T) {
+	ai := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":""},"finish_reason":"content_filter"}]}`)
+	})
+
+	_, err := ai.Complete(context.Background(), &Prompt{User: "greet"})
+	if !errors.Is(err, ErrContentFiltered) {
+		t.Errorf("Complete() error = %v, want %v", err, ErrContentFiltered)
+	}
+}
+
+func TestOpenAI_Complete_noChoices(t *testing.T) {
+	ai := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[]}`)
+	})
+
+	_, err := ai.Complete(context.Background(), &Prompt{User: "greet"})
+	if !errors.Is(err, ErrUpstream) {
+		t.Errorf("Complete() error = %v, want %v", err, ErrUpstream)
+	}
+}
+
+func TestOpenAI_Stream_stalled(t *testing.T) {
+	ai := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"Hello"}}]}`+"\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	deltas := 0
+	_, err := ai.Stream(context.Background(), &Prompt{User: "greet"}, func(string) error {
+		deltas++
+		return nil
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Stream() error = %v, want %v", err, ErrTimeout)
+	}
+	if deltas != 1 {
+		t.Errorf("Stream() deltas = %d, want 1", deltas)
+	}
+}
@@ -0,0 +1,213 @@
+package proxy
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultMaxInputLength is the limit of the characters of the input, for the templates without
+// a limit of their own
+const defaultMaxInputLength = 100
+
+//go:embed prompts/*.yaml
+var prompts embed.FS
+
+// Prompts returns the filesystem with the prompt templates shipped along with the binary, at its
+// root
+func Prompts() fs.FS {
+	sub, _ := fs.Sub(prompts, "prompts")
+	return sub
+}
+
+// PromptTemplate is a named & versioned template of the prompts, loaded from a YAML file. The user
+// message is a text/template, rendered with the input as .Topic
+type PromptTemplate struct {
+	Name string `yaml:"name"`
+	// Version is incremented on every change of the template, since its completions differ
+	Version     int     `yaml:"version"`
+	Description string  `yaml:"description"`
+	System      string  `yaml:"system"`
+	User        string  `yaml:"user"`
+	Model       string  `yaml:"model"`
+	Temperature float32 `yaml:"temperature"`
+	MaxTokens   int     `yaml:"maxTokens"`
+	// MaxInputLength is the limit of the characters of the input, 100 by default
+	MaxInputLength int `yaml:"maxInputLength"`
+
+	userTemplate *template.Template
+}
+
+// Render returns the prompt of the template for the input. The input is validated, it should be a
+// single line within the limit of the template, so that it can't pass as instructions of its own
+func (pt *PromptTemplate) Render(input string) (*Prompt, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, errors.Validation("the topic is required")
+	}
+
+	if !utf8.ValidString(input) || strings.IndexFunc(input, unicode.IsControl) >= 0 {
+		return nil, errors.Validation("the topic should be a single line of text")
+	}
+
+	if utf8.RuneCountInString(input) > pt.MaxInputLength {
+		return nil, errors.Validation(fmt.Sprintf("the topic should be up to %d characters", pt.MaxInputLength))
+	}
+
+	user := bytes.Buffer{}
+	err := pt.userTemplate.Execute(&user, struct{ Topic string }{Topic: input})
+	if err != nil {
+		return nil, errors.InternalErr(err, fmt.Sprintf("failed to render the prompt template '%s'", pt.ID()))
+	}
+
+	return &Prompt{
+		Template:    pt.Name,
+		Version:     pt.Version,
+		Input:       input,
+		System:      strings.TrimSpace(pt.System),
+		User:        strings.TrimSpace(user.String()),
+		Model:       pt.Model,
+		Temperature: pt.Temperature,
+		MaxTokens:   pt.MaxTokens,
+	}, nil
+}
+
+// ID identifies the template & its version, e.g. paragraph@2
+func (pt *PromptTemplate) ID() string {
+	return fmt.Sprintf("%s@%d", pt.Name, pt.Version)
+}
+
+func (pt *PromptTemplate) init() error {
+	if pt.Name == "" {
+		return errors.Validation("name is required")
+	}
+
+	if pt.Version < 1 {
+		return errors.Validation("version should be at least 1")
+	}
+
+	if strings.TrimSpace(pt.User) == "" {
+		return errors.Validation("user is required")
+	}
+
+	if pt.Temperature < 0 || pt.Temperature > 2 {
+		return errors.Validation("temperature should be between 0 & 2")
+	}
+
+	if pt.MaxTokens < 0 || pt.MaxInputLength < 0 {
+		return errors.Validation("maxTokens & maxInputLength should not be negative")
+	}
+
+	if pt.MaxInputLength == 0 {
+		pt.MaxInputLength = defaultMaxInputLength
+	}
+
+	user, err := template.New(pt.Name).Option("missingkey=error").Parse(pt.User)
+	if err != nil {
+		return errors.ValidationErr(err, "invalid user template")
+	}
+	pt.userTemplate = user
+
+	return nil
+}
+
+// PromptRegistry holds all the versions of the prompt templates, by their name
+type PromptRegistry struct {
+	// templates are the versions of every template, the latest version first
+	templates   map[string][]*PromptTemplate
+	defaultName string
+}
+
+// Get returns the version of the template by its name. The default template is returned when
+// the name is empty, & the latest version when the version is 0
+func (pr *PromptRegistry) Get(name string, version int) (*PromptTemplate, error) {
+	if name == "" {
+		name = pr.defaultName
+	}
+
+	versions, ok := pr.templates[name]
+	if !ok {
+		return nil, errors.Validation(fmt.Sprintf("unknown prompt template '%s'", name))
+	}
+
+	if version == 0 {
+		return versions[0], nil
+	}
+
+	for _, pt := range versions {
+		if pt.Version == version {
+			return pt, nil
+		}
+	}
+
+	return nil, errors.Validation(fmt.Sprintf("unknown version %d of the prompt template '%s'", version, name))
+}
+
+// LoadPrompts returns the registry of all the prompt templates in the YAML files at the root of
+// fsys, e.g. Prompts(). defaultName is the name of the template used when none is asked for
+func LoadPrompts(fsys fs.FS, defaultName string) (*PromptRegistry, error) {
+	files, err := fs.Glob(fsys, "*.yaml")
+	if err != nil {
+		return nil, errors.InternalErr(err, "failed to list the prompt templates")
+	}
+
+	pr := &PromptRegistry{
+		templates:   make(map[string][]*PromptTemplate),
+		defaultName: defaultName,
+	}
+	ids := make(map[string]string, len(files))
+	for _, file := range files {
+		pt, err := loadPrompt(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		if dup, ok := ids[pt.ID()]; ok {
+			return nil, errors.Validation(fmt.Sprintf("prompt template '%s' is in both '%s' & '%s'", pt.ID(), dup, file))
+		}
+		ids[pt.ID()] = file
+		pr.templates[pt.Name] = append(pr.templates[pt.Name], pt)
+	}
+
+	for _, versions := range pr.templates {
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i].Version > versions[j].Version
+		})
+	}
+
+	_, ok := pr.templates[defaultName]
+	if !ok {
+		return nil, errors.Validation(fmt.Sprintf("the default prompt template '%s' is not found", defaultName))
+	}
+
+	return pr, nil
+}
+
+func loadPrompt(fsys fs.FS, file string) (*PromptTemplate, error) {
+	raw, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, errors.InternalErr(err, fmt.Sprintf("failed to read the prompt template '%s'", file))
+	}
+
+	pt := &PromptTemplate{}
+	err = yaml.Unmarshal(raw, pt)
+	if err != nil {
+		return nil, errors.ValidationErr(err, fmt.Sprintf("invalid prompt template '%s'", file))
+	}
+
+	err = pt.init()
+	if err != nil {
+		return nil, errors.ValidationErr(err, fmt.Sprintf("invalid prompt template '%s'", file))
+	}
+
+	return pt, nil
+}
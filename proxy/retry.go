@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/circuitbreaker"
+	"github.com/mohamedveron/go_app_template/internal/pkg/logger"
+	"github.com/mohamedveron/go_app_template/internal/pkg/metrics"
+)
+
+// RetryConfig is the configuration of the retries of the requests failed with a transient error
+type RetryConfig struct {
+	// MaxRetries is the maximum number of retries of a request, 0 disables the retries
+	MaxRetries int
+	// BaseDelay is the delay before the first retry, which doubles with every retry
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between the retries
+	MaxDelay time.Duration
+	// Budget is the total time the retries of a request can take, including the delays. A retry
+	// which would exceed it is not made
+	Budget time.Duration
+}
+
+// retryTransport retries the requests failed with a transient error, i.e. 429 & 5xx responses &
+// network errors, with jittered exponential backoff. The delay in the Retry-After header of the
+// response, if any, is honored instead of the backoff. Every attempt is made through the circuit
+// breaker, so that the requests fail fast once the API is failing. The requests of the OpenAI API
+// used have no side effects, so they're all retried
+type retryTransport struct {
+	next    http.RoundTripper
+	cfg     RetryConfig
+	breaker *circuitbreaker.Breaker
+}
+
+func (rt *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a request whose body can't be read again can't be retried
+	retryable := req.Body == nil || req.GetBody != nil
+
+	start := time.Now()
+	for retry := 0; ; retry++ {
+		attempt, err := rewind(req, retry)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := rt.attempt(attempt)
+		if errors.Is(err, circuitbreaker.ErrOpen) || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		reason := transientFailure(resp, err)
+		if reason == "" || !retryable || retry >= rt.cfg.MaxRetries {
+			return resp, err
+		}
+
+		delay := rt.backoff(retry)
+		if after, ok := retryAfter(resp); ok {
+			delay = after
+		}
+		if time.Since(start)+delay > rt.cfg.Budget {
+			return resp, err
+		}
+
+		// the response of the failed attempt is discarded, so that the connection can be reused
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		metrics.ObserveOpenAIRetry(reason)
+		logger.FromContext(req.Context()).Warnw(
+			"retrying OpenAI request",
+			"reason", reason,
+			"retry", retry+1,
+			"delay", delay,
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt makes the request through the circuit breaker, & records its outcome
+func (rt *retryTransport) attempt(req *http.Request) (*http.Response, error) {
+	done, err := rt.breaker.Allow()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rt.next.RoundTrip(req)
+	switch {
+	case req.Context().Err() != nil:
+		// the caller giving up tells nothing of the API
+		done(circuitbreaker.Abandoned)
+	case transientFailure(resp, err) != "":
+		done(circuitbreaker.Failure)
+	default:
+		done(circuitbreaker.Success)
+	}
+
+	return resp, err
+}
+
+// backoff returns the delay before the retry, a random duration up to the exponential backoff
+// ("full jitter"), so that the retries of concurrent requests are spread
+func (rt *retryTransport) backoff(retry int) time.Duration {
+	ceiling := rt.cfg.MaxDelay
+	// the shifted delay overflows to be negative, or 0, for large retries
+	if delay := rt.cfg.BaseDelay << retry; retry < 63 && delay > 0 && delay < ceiling {
+		ceiling = delay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// rewind returns the request to be sent for the retry, with a new body, since the body of the
+// previous attempt is consumed
+func rewind(req *http.Request, retry int) (*http.Request, error) {
+	if retry == 0 || req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	attempt := req.Clone(req.Context())
+	attempt.Body = body
+	return attempt, nil
+}
+
+// transientFailure returns the reason of the failure if the request failed with an error which
+// may not happen again, or an empty string otherwise
+func transientFailure(resp *http.Response, err error) string {
+	switch {
+	case err != nil:
+		return "network_error"
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "rate_limited"
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return "server_error"
+	default:
+		return ""
+	}
+}
+
+// retryAfter returns the delay in the Retry-After header of the response, in seconds or as a date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	delay := time.Until(date)
+	if delay < 0 {
+		delay = 0
+	}
+
+	return delay, true
+}
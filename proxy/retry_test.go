@@ -0,0 +1,189 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mohamedveron/go_app_template/internal/pkg/circuitbreaker"
+	"github.com/mohamedveron/go_app_template/internal/pkg/errors"
+)
+
+func withRetries(cfg *OpenAIConfig) {
+	cfg.Timeout = 5 * time.Second
+	cfg.Retry = RetryConfig{
+		MaxRetries: 3,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   10 * time.Millisecond,
+		Budget:     time.Second,
+	}
+}
+
+// failingHandler fails the first failures requests with status, & completes the rest
+func failingHandler(calls *int32, failures int32, status int, retryAfter string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(calls, 1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"error":{"message":"failed","type":"error"}}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}]}`)
+	}
+}
+
+func TestOpenAI_retry(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int32
+		status     int
+		retryAfter string
+		wantCalls  int32
+		wantErr    error
+	}{
+		{
+			name:      "rate limited",
+			failures:  2,
+			status:    http.StatusTooManyRequests,
+			wantCalls: 3,
+		},
+		{
+			name:       "honors Retry-After",
+			failures:   1,
+			status:     http.StatusServiceUnavailable,
+			retryAfter: "0",
+			wantCalls:  2,
+		},
+		{
+			name:       "Retry-After beyond the budget",
+			failures:   1,
+			status:     http.StatusTooManyRequests,
+			retryAfter: "60",
+			wantCalls:  1,
+			wantErr:    ErrRateLimited,
+		},
+		{
+			name:      "retries exhausted",
+			failures:  10,
+			status:    http.StatusBadGateway,
+			wantCalls: 4,
+			wantErr:   ErrUpstream,
+		},
+		{
+			name:      "not transient",
+			failures:  1,
+			status:    http.StatusBadRequest,
+			wantCalls: 1,
+			wantErr:   ErrUpstream,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := int32(0)
+			ai := newTestOpenAI(t, failingHandler(&calls, tt.failures, tt.status, tt.retryAfter), withRetries)
+
+			got, err := ai.Complete(context.Background(), &Prompt{User: "greet"})
+			if tt.wantErr == nil && (err != nil || got != "Hello") {
+				t.Errorf("Complete() = %q, %v, want Hello", got, err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Complete() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestOpenAI_circuitBreaker(t *testing.T) {
+	calls := int32(0)
+	ai := newTestOpenAI(t, failingHandler(&calls, 2, http.StatusInternalServerError, ""), func(cfg *OpenAIConfig) {
+		cfg.Breaker = circuitbreaker.Config{
+			Window:       2,
+			MinCalls:     2,
+			FailureRatio: 0.5,
+			OpenTimeout:  50 * time.Millisecond,
+		}
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := ai.Complete(context.Background(), &Prompt{User: "greet"})
+		if !errors.Is(err, ErrUpstream) {
+			t.Fatalf("Complete() error = %v, want %v", err, ErrUpstream)
+		}
+	}
+
+	// the calls fail fast, without calling the API, while the breaker is open
+	_, err := ai.Complete(context.Background(), &Prompt{User: "greet"})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Complete() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if status, _, _ := errors.HTTPStatusCodeMessage(err); status != http.StatusServiceUnavailable {
+		t.Errorf("Complete() error status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	if ai.checkCircuit(context.Background()) == nil {
+		t.Error("checkCircuit() should fail while the breaker is open")
+	}
+
+	// the probe succeeds once the open timeout elapses, & closes the breaker
+	time.Sleep(50 * time.Millisecond)
+	got, err := ai.Complete(context.Background(), &Prompt{User: "greet"})
+	if err != nil || got != "Hello" {
+		t.Errorf("Complete() = %q, %v, want Hello", got, err)
+	}
+	if ai.breaker.State() != circuitbreaker.StateClosed {
+		t.Errorf("breaker state = %s, want %s", ai.breaker.State(), circuitbreaker.StateClosed)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "none"},
+		{name: "seconds", value: "3", want: 3 * time.Second, wantOK: true},
+		{name: "past date", value: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0, wantOK: true},
+		{name: "invalid", value: "soon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.value != "" {
+				resp.Header.Set("Retry-After", tt.value)
+			}
+
+			got, ok := retryAfter(resp)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("retryAfter() = %s, %v, want %s, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_backoff(t *testing.T) {
+	rt := &retryTransport{cfg: RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}}
+
+	for retry := 0; retry < 64; retry++ {
+		ceiling := time.Second
+		if retry < 4 {
+			ceiling = 100 * time.Millisecond << retry
+		}
+		if got := rt.backoff(retry); got < 0 || got >= ceiling {
+			t.Errorf("backoff(%d) = %s, want in [0, %s)", retry, got, ceiling)
+		}
+	}
+}
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS users_createdat_id;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS users_createdat_id ON Users (createdAt, id);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS LLMCache (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    expiresAt timestamptz NOT NULL,
+    accessedAt timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS llmcache_accessedat ON LLMCache (accessedAt);
@@ -0,0 +1,16 @@
+// Package schemas embeds the SQL migrations, so that they're shipped along with the binary
+package schemas
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrations returns the filesystem with all the SQL migrations at its root
+func Migrations() fs.FS {
+	sub, _ := fs.Sub(migrations, "migrations")
+	return sub
+}